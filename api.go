@@ -0,0 +1,461 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/DHowett/avantgarde/tv"
+)
+
+// Error codes reported in the "code" member of API error responses.
+const (
+	codeInvalidRequest   = "invalid_request"
+	codeInvalidValue     = "invalid_value"
+	codeNotFound         = "not_found"
+	codeMethodNotAllowed = "method_not_allowed"
+	codeUnsupported      = "unsupported"
+	codeTimeout          = "timeout"
+	codeDisconnected     = "disconnected"
+	codeInternal         = "internal"
+	codeUnauthorized     = "unauthorized"
+	codeForbidden        = "forbidden"
+)
+
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type apiErrorResponse struct {
+	Error apiError `json:"error"`
+}
+
+// apiOp is the body of a request to perform an operation, e.g.
+// {"attribute": "volume", "operator": "set", "value": 15}.
+type apiOp struct {
+	Attribute string          `json:"attribute"`
+	Operator  string          `json:"operator"`
+	Value     json.RawMessage `json:"value"`
+}
+
+type apiInput struct {
+	Connection string `json:"connection"`
+	Number     int    `json:"number"`
+}
+
+// apiRawReply is the response to a raw operation. Reply is empty for TVs
+// that do not pass their replies on.
+type apiRawReply struct {
+	Reply string `json:"reply"`
+}
+
+type apiCapability struct {
+	Attribute string     `json:"attribute"`
+	Operators []string   `json:"operators"`
+	Min       *int       `json:"min,omitempty"`
+	Max       *int       `json:"max,omitempty"`
+	Inputs    []apiInput `json:"inputs,omitempty"`
+}
+
+type apiTV struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name,omitempty"`
+	Model string `json:"model"`
+	// Connection is "connected", "disconnected", or "unknown" for TVs that
+	// cannot tell.
+	Connection   string          `json:"connection"`
+	Capabilities []apiCapability `json:"capabilities,omitempty"`
+}
+
+// connectionNames gives the canonical name of each connection type.
+var connectionNames = map[tv.Connection]string{}
+
+func init() {
+	for _, name := range tvInputNames {
+		connectionNames[inputNameToTV[name]] = name
+	}
+}
+
+func newAPICapability(c tv.Capability) apiCapability {
+	ac := apiCapability{Attribute: c.Attribute.String()}
+	for _, o := range c.Operators {
+		ac.Operators = append(ac.Operators, o.String())
+	}
+	if c.Max > c.Min {
+		lo, hi := c.Min, c.Max
+		ac.Min, ac.Max = &lo, &hi
+	}
+	for _, i := range c.Inputs {
+		ac.Inputs = append(ac.Inputs, apiInput{connectionNames[i.Connection], i.Number})
+	}
+	return ac
+}
+
+// connectionState is "connected", "disconnected", or "unknown" for TVs that
+// cannot tell.
+func connectionState(id int) string {
+	c, ok := tvs[id].(tv.Connector)
+	if !ok {
+		return "unknown"
+	}
+	if c.Connected() {
+		return "connected"
+	}
+	return "disconnected"
+}
+
+func newAPITV(id int) *apiTV {
+	t := &apiTV{
+		ID:         id,
+		Name:       tvConfigs[id].V.Name,
+		Model:      tvConfigs[id].V.Model,
+		Connection: connectionState(id),
+	}
+	if c, ok := tvs[id].(tv.Capable); ok {
+		for _, capability := range c.Capabilities() {
+			if capability.Attribute == tv.Raw && !rawAllowed(id) {
+				continue
+			}
+			t.Capabilities = append(t.Capabilities, newAPICapability(capability))
+		}
+	}
+	return t
+}
+
+// serveTVList describes every configured TV. It is served both at /tvs and
+// at /api/v1/tvs.
+func serveTVList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeAPIError(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "use GET")
+		return
+	}
+
+	g, ok := auth.authenticate(w, r, apiFailure(w))
+	if !ok {
+		return
+	}
+
+	// Only list the TVs that the token grants access to.
+	list := []*apiTV{}
+	for i := range tvs {
+		if g == nil || g.allowsTV(i) {
+			list = append(list, newAPITV(i))
+		}
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// classifyError maps an error returned by a TV onto an API error code and
+// the HTTP status that goes with it.
+func classifyError(err error) (string, int) {
+	switch {
+	case errors.Is(err, tv.ErrUnsupported):
+		return codeUnsupported, http.StatusNotImplemented
+	case errors.Is(err, tv.ErrInvalidValue):
+		return codeInvalidValue, http.StatusBadRequest
+	case errors.Is(err, tv.ErrTimeout):
+		return codeTimeout, http.StatusGatewayTimeout
+	case errors.Is(err, tv.ErrDisconnected):
+		return codeDisconnected, http.StatusServiceUnavailable
+	}
+	return codeInternal, http.StatusInternalServerError
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, code string, message string) {
+	writeJSON(w, status, &apiErrorResponse{apiError{code, message}})
+}
+
+func writeTVError(w http.ResponseWriter, err error) {
+	code, status := classifyError(err)
+	writeAPIError(w, status, code, err.Error())
+}
+
+// attributeOperators lists the operators that make sense for each
+// attribute, whatever the TV. Drivers are only ever sent these, so that
+// they may rely on, say, a Set of power carrying a bool.
+var attributeOperators = map[tv.Attribute][]tv.Operator{
+	tv.Power:            {tv.Set, tv.Toggle, tv.Query},
+	tv.Mute:             {tv.Set, tv.Toggle, tv.Query},
+	tv.Screen:           {tv.Set, tv.Toggle, tv.Query},
+	tv.OSD:              {tv.Set, tv.Toggle, tv.Query},
+	tv.Lock:             {tv.Set, tv.Toggle, tv.Query},
+	tv.PIP:              {tv.Set, tv.Toggle, tv.Query},
+	tv.Volume:           {tv.Set, tv.Increment, tv.Decrement},
+	tv.Contrast:         {tv.Set, tv.Increment, tv.Decrement},
+	tv.Brightness:       {tv.Set, tv.Increment, tv.Decrement},
+	tv.Color:            {tv.Set, tv.Increment, tv.Decrement},
+	tv.Tint:             {tv.Set, tv.Increment, tv.Decrement},
+	tv.Sharpness:        {tv.Set, tv.Increment, tv.Decrement},
+	tv.AudioBalance:     {tv.Set, tv.Increment, tv.Decrement},
+	tv.ColorTemperature: {tv.Set, tv.Increment, tv.Decrement},
+	tv.Backlight:        {tv.Set, tv.Increment, tv.Decrement},
+	tv.Input:            {tv.Set},
+	tv.Tuning:           {tv.Set},
+	tv.Raw:              {tv.Set},
+}
+
+func hasOperator(operators []tv.Operator, operator tv.Operator) bool {
+	for _, o := range operators {
+		if o == operator {
+			return true
+		}
+	}
+	return false
+}
+
+// checkOperator rejects operators that make no sense for an attribute,
+// like toggling the volume.
+func checkOperator(attr tv.Attribute, operator tv.Operator) error {
+	if !hasOperator(attributeOperators[attr], operator) {
+		return fmt.Errorf("%s cannot be given %s: %w", attr, operator, tv.ErrInvalidValue)
+	}
+	return nil
+}
+
+// checkSupported rejects operations that a TV does not list among its
+// capabilities. TVs that cannot report their capabilities are sent
+// anything that passes checkOperator.
+func checkSupported(t tv.TV, op *tv.Op) error {
+	c, ok := t.(tv.Capable)
+	if !ok {
+		return nil
+	}
+	for _, capability := range c.Capabilities() {
+		if capability.Attribute == op.Attribute && hasOperator(capability.Operators, op.Operator) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s %s: %w", op.Attribute, op.Operator, tv.ErrUnsupported)
+}
+
+// maxStep bounds how far one Increment or Decrement may step.
+const maxStep = 100
+
+// doOp performs an operation on a TV. Drivers step by one whatever an
+// Increment or Decrement asks for, as a remote's button would, so a larger
+// step is sent as that many steps of one.
+func doOp(t tv.TV, op *tv.Op) error {
+	if op.Operator != tv.Increment && op.Operator != tv.Decrement {
+		return t.Do(op)
+	}
+	n, _ := op.Value.(int)
+	if n < 1 || n > maxStep {
+		return fmt.Errorf("%s: cannot step by %v: %w", op.Attribute, op.Value, tv.ErrInvalidValue)
+	}
+	for i := 0; i < n; i++ {
+		if err := t.Do(&tv.Op{op.Attribute, op.Operator, 1}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func invalidValue(attr tv.Attribute, err error) error {
+	return fmt.Errorf("%s: %v: %w", attr, err, tv.ErrInvalidValue)
+}
+
+// decodeValue converts the JSON value in an API request into the type that
+// TVs expect for the given attribute and operator.
+func decodeValue(attr tv.Attribute, operator tv.Operator, raw json.RawMessage) (interface{}, error) {
+	switch operator {
+	case tv.Toggle, tv.Query:
+		return nil, nil
+	case tv.Increment, tv.Decrement:
+		step := 1
+		if len(raw) != 0 {
+			if err := json.Unmarshal(raw, &step); err != nil {
+				return nil, invalidValue(attr, err)
+			}
+		}
+		return step, nil
+	}
+
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("%s: missing value: %w", attr, tv.ErrInvalidValue)
+	}
+
+	switch attr {
+	case tv.Power, tv.Mute, tv.Screen, tv.OSD, tv.Lock, tv.PIP:
+		var v bool
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return nil, invalidValue(attr, err)
+		}
+		return v, nil
+	case tv.Volume, tv.Contrast, tv.Brightness, tv.Color, tv.Tint, tv.Sharpness,
+		tv.AudioBalance, tv.ColorTemperature, tv.Backlight:
+		var v int
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return nil, invalidValue(attr, err)
+		}
+		return v, nil
+	case tv.Input:
+		var v apiInput
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return nil, invalidValue(attr, err)
+		}
+		connection, ok := inputNameToTV[v.Connection]
+		if !ok {
+			return nil, invalidValue(attr, fmt.Errorf("unknown connection %q", v.Connection))
+		}
+		return tv.InputNumber{connection, v.Number}, nil
+	case tv.Tuning:
+		// Channels are "7" or "7.1"; accept bare numbers for analog ones.
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			var n uint
+			if err := json.Unmarshal(raw, &n); err != nil {
+				return nil, invalidValue(attr, err)
+			}
+			s = strconv.FormatUint(uint64(n), 10)
+		}
+		ch, err := ParseChannel(s)
+		if err != nil {
+			return nil, invalidValue(attr, err)
+		}
+		return tv.Tune{0x01, ch}, nil
+	case tv.Raw:
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, invalidValue(attr, err)
+		}
+		if s == "" {
+			return nil, fmt.Errorf("%s: empty command: %w", attr, tv.ErrInvalidValue)
+		}
+		return []byte(s), nil
+	}
+	return nil, fmt.Errorf("%s: %w", attr, tv.ErrUnsupported)
+}
+
+func (o *apiOp) toOp() (*tv.Op, error) {
+	attr, err := tv.ParseAttribute(o.Attribute)
+	if err != nil {
+		return nil, err
+	}
+	operator := tv.Set
+	if o.Operator != "" {
+		operator, err = tv.ParseOperator(o.Operator)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := checkOperator(attr, operator); err != nil {
+		return nil, err
+	}
+	value, err := decodeValue(attr, operator, o.Value)
+	if err != nil {
+		return nil, err
+	}
+	return &tv.Op{attr, operator, value}, nil
+}
+
+// apiServer serves the JSON API under /api/v1. TVs are addressed by name or
+// number as /api/v1/tv/{id}/op and /api/v1/tv/{id}/state, and listed at
+// /api/v1/tvs.
+type apiServer struct{}
+
+func (api *apiServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, ok := auth.authenticate(w, r, apiFailure(w)); !ok {
+		return
+	}
+
+	comp := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/"), "/")
+	if len(comp) == 1 && comp[0] == "tvs" {
+		serveTVList(w, r)
+		return
+	}
+	if len(comp) != 3 || comp[0] != "tv" {
+		writeAPIError(w, http.StatusNotFound, codeNotFound, "no such endpoint")
+		return
+	}
+	tvId, ok := findTV(comp[1])
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, codeNotFound, fmt.Sprintf("no such tv %q", comp[1]))
+		return
+	}
+
+	switch comp[2] {
+	case "op":
+		api.serveOp(w, r, tvId)
+	case "state":
+		if !auth.authorize(w, r, tvId, 0, apiFailure(w)) {
+			return
+		}
+		api.serveState(w, r, tvs[tvId])
+	default:
+		writeAPIError(w, http.StatusNotFound, codeNotFound, "no such endpoint")
+	}
+}
+
+func (api *apiServer) serveOp(w http.ResponseWriter, r *http.Request, tvId int) {
+	if r.Method != "POST" {
+		writeAPIError(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "use POST")
+		return
+	}
+
+	var req apiOp
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+	op, err := req.toOp()
+	if err != nil {
+		if errors.Is(err, tv.ErrInvalidValue) || errors.Is(err, tv.ErrUnsupported) {
+			writeTVError(w, err)
+		} else {
+			writeAPIError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		}
+		return
+	}
+	if !auth.authorize(w, r, tvId, op.Attribute, apiFailure(w)) {
+		return
+	}
+
+	if op.Attribute == tv.Raw {
+		reply, err := sendRaw(r, tvId, op.Value.([]byte))
+		if errors.Is(err, errRawDisabled) {
+			writeAPIError(w, http.StatusForbidden, codeForbidden, err.Error())
+			return
+		} else if err != nil {
+			writeTVError(w, err)
+			return
+		}
+		hub.changed(tvId)
+		writeJSON(w, http.StatusOK, &apiRawReply{string(reply)})
+		return
+	}
+
+	if err := checkSupported(tvs[tvId], op); err != nil {
+		writeTVError(w, err)
+		return
+	}
+	if err := doOp(tvs[tvId], op); err != nil {
+		writeTVError(w, err)
+		return
+	}
+	hub.changed(tvId)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (api *apiServer) serveState(w http.ResponseWriter, r *http.Request, t tv.TV) {
+	if r.Method != "GET" {
+		writeAPIError(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "use GET")
+		return
+	}
+
+	state, err := t.State()
+	if err != nil {
+		writeTVError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, state)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DHowett/avantgarde/tv"
+)
+
+func TestAPIOp(t *testing.T) {
+	fake := &fakeTV{}
+	defer useFakeTVs(fake)()
+
+	tests := []struct {
+		body   string
+		status int
+		op     *tv.Op
+	}{
+		{`{"attribute":"volume","operator":"set","value":15}`, http.StatusNoContent, &tv.Op{tv.Volume, tv.Set, 15}},
+		{`{"attribute":"volume","operator":"increment"}`, http.StatusNoContent, &tv.Op{tv.Volume, tv.Increment, 1}},
+		{`{"attribute":"power","value":true}`, http.StatusNoContent, &tv.Op{tv.Power, tv.Set, true}},
+		{`{"attribute":"input","value":{"connection":"hdmi","number":2}}`, http.StatusNoContent, &tv.Op{tv.Input, tv.Set, tv.InputNumber{tv.HDMI, 2}}},
+		{`{"attribute":"volume","value":"loud"}`, http.StatusBadRequest, nil},
+		{`{"attribute":"input","value":{"connection":"hdmx","number":2}}`, http.StatusBadRequest, nil},
+		{`{"attribute":"frobnicate","value":1}`, http.StatusBadRequest, nil},
+		{`{"attribute":"mute","operator":"toggle"}`, http.StatusNoContent, &tv.Op{tv.Mute, tv.Toggle, nil}},
+		{`{"attribute":"raw","operator":"toggle"}`, http.StatusBadRequest, nil},
+		{`{"attribute":"volume","operator":"toggle"}`, http.StatusBadRequest, nil},
+		{`{"attribute":"power","operator":"toggle"}`, http.StatusNotImplemented, nil},
+		{`{"attribute":"screen","value":true}`, http.StatusNotImplemented, nil},
+		{`{`, http.StatusBadRequest, nil},
+	}
+
+	api := &apiServer{}
+	for _, test := range tests {
+		fake.ops = nil
+		w := httptest.NewRecorder()
+		api.ServeHTTP(w, httptest.NewRequest("POST", "/api/v1/tv/0/op", strings.NewReader(test.body)))
+		if w.Code != test.status {
+			t.Errorf("Got %d instead of %d for %s!", w.Code, test.status, test.body)
+			continue
+		}
+		if test.op == nil {
+			if !strings.Contains(w.Body.String(), `"code"`) {
+				t.Errorf("Got %q, not a JSON error, for %s!", w.Body.String(), test.body)
+			}
+			continue
+		}
+		if len(fake.ops) != 1 || fmt.Sprint(*fake.ops[0]) != fmt.Sprint(*test.op) {
+			t.Errorf("Got %v instead of %v for %s!", fake.ops, *test.op, test.body)
+		}
+	}
+}
+
+func TestAPIErrors(t *testing.T) {
+	fake := &fakeTV{}
+	defer useFakeTVs(fake)()
+
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{fmt.Errorf("fake: %w", tv.ErrUnsupported), http.StatusNotImplemented, codeUnsupported},
+		{fmt.Errorf("fake: %w", tv.ErrInvalidValue), http.StatusBadRequest, codeInvalidValue},
+		{fmt.Errorf("fake: %w", tv.ErrTimeout), http.StatusGatewayTimeout, codeTimeout},
+		{fmt.Errorf("fake: %w", tv.ErrDisconnected), http.StatusServiceUnavailable, codeDisconnected},
+		{fmt.Errorf("fake: on fire"), http.StatusInternalServerError, codeInternal},
+	}
+
+	api := &apiServer{}
+	for _, test := range tests {
+		fake.err = test.err
+		w := httptest.NewRecorder()
+		api.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/tv/0/state", nil))
+		if w.Code != test.status || !strings.Contains(w.Body.String(), `"code":"`+test.code+`"`) {
+			t.Errorf("Got %d %q instead of %d %s for %v!", w.Code, w.Body.String(), test.status, test.code, test.err)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/tv/7/state", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Got %d instead of %d for a missing TV!", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestTVNames(t *testing.T) {
+	fakes := []*fakeTV{{}, {}}
+	defer useFakeTVs(fakes[0], fakes[1])()
+
+	for _, path := range []string{"/api/v1/tv/fake1/op", "/api/v1/tv/1/op"} {
+		fakes[1].ops = nil
+		w := httptest.NewRecorder()
+		(&apiServer{}).ServeHTTP(w, httptest.NewRequest("POST", path, strings.NewReader(`{"attribute":"power","value":true}`)))
+		if w.Code != http.StatusNoContent || len(fakes[1].ops) != 1 {
+			t.Errorf("Got %d and %d ops instead of %d and 1 op for %s!", w.Code, len(fakes[1].ops), http.StatusNoContent, path)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	serveTVList(w, httptest.NewRequest("GET", "/tvs", nil))
+	want := `{"id":1,"name":"fake1","model":"fake","connection":"unknown","capabilities":[{"attribute":"power","operators":["set"]},{"attribute":"volume","operators":["set","increment","decrement"],"min":0,"max":100},{"attribute":"mute","operators":["set","toggle"]},{"attribute":"input","operators":["set"],"inputs":[{"connection":"hdmi","number":1}]}]}`
+	if !strings.Contains(w.Body.String(), want) {
+		t.Errorf("Got %s, which does not describe %s!", w.Body.String(), want)
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/DHowett/avantgarde/tv"
+)
+
+// TokenConfig grants the holder of a token access to some or all TVs and
+// attributes. An empty list of TVs or attributes grants all of them.
+type TokenConfig struct {
+	Name       string
+	Token      string
+	TVs        []string `yaml:"tvs"`
+	Attributes []string
+}
+
+type grant struct {
+	name  string
+	token []byte
+	// tvs and attrs are nil when the grant is unrestricted.
+	tvs   map[int]bool
+	attrs map[tv.Attribute]bool
+}
+
+// authorizer checks requests against the configured tokens. A nil
+// authorizer lets every request through.
+type authorizer struct {
+	grants []*grant
+}
+
+// auth is nil unless tokens are configured.
+var auth *authorizer
+
+func newAuthorizer(cfgs []TokenConfig) (*authorizer, error) {
+	a := &authorizer{}
+	for _, c := range cfgs {
+		if c.Token == "" {
+			return nil, fmt.Errorf("token `%s` is empty", c.Name)
+		}
+		g := &grant{name: c.Name, token: []byte(c.Token)}
+		if len(c.TVs) > 0 {
+			g.tvs = make(map[int]bool)
+			for _, id := range c.TVs {
+				i, ok := findTV(id)
+				if !ok {
+					return nil, fmt.Errorf("token `%s`: no such tv `%s`", c.Name, id)
+				}
+				g.tvs[i] = true
+			}
+		}
+		if len(c.Attributes) > 0 {
+			g.attrs = make(map[tv.Attribute]bool)
+			for _, name := range c.Attributes {
+				attr, err := tv.ParseAttribute(name)
+				if err != nil {
+					return nil, fmt.Errorf("token `%s`: %v", c.Name, err)
+				}
+				g.attrs[attr] = true
+			}
+		}
+		a.grants = append(a.grants, g)
+	}
+	return a, nil
+}
+
+// requestToken returns the token presented as "Authorization: Bearer" or
+// as an X-API-Key header.
+func requestToken(r *http.Request) string {
+	if h := r.Header.Get("Authorization"); strings.HasPrefix(h, "Bearer ") {
+		return strings.TrimPrefix(h, "Bearer ")
+	}
+	return r.Header.Get("X-API-Key")
+}
+
+func (a *authorizer) grantFor(r *http.Request) *grant {
+	return a.grantForToken(requestToken(r))
+}
+
+// grantForToken returns the grant for a token, or nil if there is none.
+func (a *authorizer) grantForToken(t string) *grant {
+	token := []byte(t)
+	if len(token) == 0 {
+		return nil
+	}
+	var found *grant
+	for _, g := range a.grants {
+		// Compare against every token, so that timing does not reveal
+		// which one matched.
+		if subtle.ConstantTimeCompare(token, g.token) == 1 {
+			found = g
+		}
+	}
+	return found
+}
+
+func (g *grant) allowsTV(id int) bool {
+	return g.tvs == nil || g.tvs[id]
+}
+
+// allows reports whether the grant covers an attribute of a TV. Endpoints
+// that change a TV without being tied to one attribute, such as /pair,
+// are only open to grants that cover every attribute.
+func (g *grant) allows(id int, attr tv.Attribute, write bool) bool {
+	if !g.allowsTV(id) {
+		return false
+	}
+	if g.attrs == nil {
+		return true
+	}
+	if attr == 0 {
+		return !write
+	}
+	return g.attrs[attr]
+}
+
+func logUnauthorized(r *http.Request, g *grant, why string) {
+	logUnauthorizedCall(r.Method+" "+r.URL.Path, r.RemoteAddr, g, why)
+}
+
+// logUnauthorizedCall logs a rejected call, described as the request line
+// or the RPC method.
+func logUnauthorizedCall(call, from string, g *grant, why string) {
+	name := "no valid token"
+	if g != nil {
+		name = "token `" + g.name + "`"
+	}
+	log.Printf("unauthorized: %s from %s with %s: %s\n", call, from, name, why)
+}
+
+// authenticate checks that a request carries a valid token and returns its
+// grant. It reports the failure and returns false if it does not.
+func (a *authorizer) authenticate(w http.ResponseWriter, r *http.Request, fail func(int, string)) (*grant, bool) {
+	if a == nil {
+		return nil, true
+	}
+	g := a.grantFor(r)
+	if g == nil {
+		logUnauthorized(r, nil, "not authenticated")
+		w.Header().Set("WWW-Authenticate", `Bearer realm="avantgarde"`)
+		fail(http.StatusUnauthorized, "a valid token is required")
+		return nil, false
+	}
+	return g, true
+}
+
+// authorize checks that a request may act on an attribute of a TV; attr is
+// 0 for endpoints that are not tied to one. It reports the failure and
+// returns false if the request may not proceed.
+func (a *authorizer) authorize(w http.ResponseWriter, r *http.Request, id int, attr tv.Attribute, fail func(int, string)) bool {
+	g, ok := a.authenticate(w, r, fail)
+	if !ok || g == nil {
+		return ok
+	}
+	if !g.allows(id, attr, r.Method != "GET") {
+		what := fmt.Sprintf("tv %d", id)
+		if attr != 0 {
+			what = fmt.Sprintf("%s of tv %d", attr, id)
+		}
+		logUnauthorized(r, g, "not allowed "+what)
+		fail(http.StatusForbidden, "this token may not use "+what)
+		return false
+	}
+	return true
+}
+
+// plainFailure reports an authorization failure the way the form endpoints
+// report errors.
+func plainFailure(w http.ResponseWriter) func(int, string) {
+	return func(status int, message string) {
+		w.WriteHeader(status)
+		w.Write([]byte(message))
+	}
+}
+
+// apiFailure reports an authorization failure as a JSON API error.
+func apiFailure(w http.ResponseWriter) func(int, string) {
+	return func(status int, message string) {
+		code := codeUnauthorized
+		if status == http.StatusForbidden {
+			code = codeForbidden
+		}
+		writeAPIError(w, status, code, message)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/DHowett/avantgarde/tv"
+)
+
+const testTokens = `
+- name: admin
+  token: admin-secret
+- name: bar
+  token: bar-secret
+  tvs: [fake1]
+  attributes: [input, volume]
+`
+
+func useTestTokens(t *testing.T) func() {
+	var cfgs []TokenConfig
+	if err := yaml.Unmarshal([]byte(testTokens), &cfgs); err != nil {
+		t.Fatalf("Failed to parse tokens: %v", err)
+	}
+	a, err := newAuthorizer(cfgs)
+	if err != nil {
+		t.Fatalf("Failed to configure tokens: %v", err)
+	}
+	auth = a
+	return func() { auth = nil }
+}
+
+func TestAuthForm(t *testing.T) {
+	defer useFakeTVs(&fakeTV{}, &fakeTV{})()
+	defer useTestTokens(t)()
+
+	sv := newTVServer()
+	for _, test := range []struct {
+		token, path, v string
+		status         int
+	}{
+		{"", "/tv/fake1/volume", "10", http.StatusUnauthorized},
+		{"wrong", "/tv/fake1/volume", "10", http.StatusUnauthorized},
+		{"admin-secret", "/tv/fake0/power", "1", http.StatusNoContent},
+		{"bar-secret", "/tv/fake1/volume", "10", http.StatusNoContent},
+		{"bar-secret", "/tv/1/volume", "10", http.StatusNoContent},
+		{"bar-secret", "/tv/fake1/power", "0", http.StatusForbidden},
+		{"bar-secret", "/tv/fake1/raw", "x", http.StatusForbidden},
+		{"bar-secret", "/tv/fake1/pair", "", http.StatusForbidden},
+		{"bar-secret", "/tv/fake0/volume", "10", http.StatusForbidden},
+	} {
+		req := httptest.NewRequest("POST", test.path, strings.NewReader(url.Values{"v": {test.v}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if test.token != "" {
+			req.Header.Set("Authorization", "Bearer "+test.token)
+		}
+		w := httptest.NewRecorder()
+		sv.ServeHTTP(w, req)
+		if w.Code != test.status {
+			t.Errorf("Got %d instead of %d for %s with %q!", w.Code, test.status, test.path, test.token)
+		}
+	}
+
+	// Reading state needs no particular attribute.
+	req := httptest.NewRequest("GET", "/tv/fake1/status", nil)
+	req.Header.Set("X-API-Key", "bar-secret")
+	w := httptest.NewRecorder()
+	sv.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Got %d instead of %d for reading state!", w.Code, http.StatusOK)
+	}
+}
+
+func TestAuthAPI(t *testing.T) {
+	fakes := []*fakeTV{{}, {}}
+	defer useFakeTVs(fakes[0], fakes[1])()
+	defer useTestTokens(t)()
+
+	api := &apiServer{}
+	do := func(token, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		api.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do("bar-secret", "POST", "/api/v1/tv/fake1/op", `{"attribute":"power","value":false}`); w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), `"forbidden"`) {
+		t.Errorf("Got %d %s instead of a 403 for a forbidden attribute!", w.Code, w.Body.String())
+	}
+	if w := do("bar-secret", "POST", "/api/v1/tv/fake1/op", `{"attribute":"input","value":{"connection":"hdmi","number":1}}`); w.Code != http.StatusNoContent {
+		t.Errorf("Got %d %s instead of a 204 for an allowed attribute!", w.Code, w.Body.String())
+	}
+	if len(fakes[1].ops) != 1 || fakes[1].ops[0].Attribute != tv.Input {
+		t.Errorf("Got %v instead of one input op!", fakes[1].ops)
+	}
+	if w := do("nope", "GET", "/api/v1/tv/fake1/state", ""); w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), `"unauthorized"`) {
+		t.Errorf("Got %d %s instead of a 401 for a bad token!", w.Code, w.Body.String())
+	}
+	if w := do("bar-secret", "GET", "/api/v1/tvs", ""); strings.Contains(w.Body.String(), "fake0") || !strings.Contains(w.Body.String(), "fake1") {
+		t.Errorf("Got %s, which should list only fake1!", w.Body.String())
+	}
+}
+
+func TestAuthConfig(t *testing.T) {
+	defer useFakeTVs(&fakeTV{})()
+
+	for _, bad := range []TokenConfig{
+		{Name: "empty"},
+		{Name: "tv", Token: "x", TVs: []string{"nowhere"}},
+		{Name: "attr", Token: "x", Attributes: []string{"warp"}},
+	} {
+		if _, err := newAuthorizer([]TokenConfig{bad}); err == nil {
+			t.Errorf("Accepted invalid token %+v!", bad)
+		}
+	}
+}
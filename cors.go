@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// CORSConfig decides which web pages may call the server from a browser.
+type CORSConfig struct {
+	// Origins lists the origins, like "https://panel.example.com", that
+	// may make requests; "*" allows any.
+	Origins []string
+	// Credentials lets pages send cookies and Authorization headers.
+	Credentials bool
+	// MaxAge is how long, in seconds, browsers may cache a preflight.
+	MaxAge int `yaml:"max_age"`
+}
+
+// defaultCORS is used when the configuration does not mention CORS. It
+// matches what the server has always done: any page may call it.
+var defaultCORS = CORSConfig{Origins: []string{"*"}}
+
+const (
+	corsMethods = "GET, POST, OPTIONS"
+	corsHeaders = "Content-Type, Authorization, X-API-Key"
+)
+
+type corsHandler struct {
+	config  CORSConfig
+	anyOrig bool
+	origins map[string]bool
+	next    http.Handler
+}
+
+// withCORS wraps a handler with the CORS policy in c. It answers every
+// OPTIONS request itself, so handlers need not.
+func withCORS(c CORSConfig, next http.Handler) http.Handler {
+	h := &corsHandler{config: c, origins: make(map[string]bool), next: next}
+	for _, o := range c.Origins {
+		if o == "*" {
+			h.anyOrig = true
+		}
+		h.origins[strings.TrimSuffix(o, "/")] = true
+	}
+	return h
+}
+
+func (h *corsHandler) allowed(origin string) bool {
+	return h.anyOrig || h.origins[origin]
+}
+
+func (h *corsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	allowed := origin != "" && h.allowed(origin)
+	preflight := r.Method == "OPTIONS" && r.Header.Get("Access-Control-Request-Method") != ""
+
+	if allowed {
+		// A wildcard cannot be combined with credentials, so name the
+		// origin instead.
+		if h.anyOrig && !h.config.Credentials {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Add("Vary", "Origin")
+		}
+		if h.config.Credentials {
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+	}
+
+	if preflight {
+		if !allowed {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Access-Control-Allow-Methods", corsMethods)
+		w.Header().Set("Access-Control-Allow-Headers", corsHeaders)
+		if h.config.MaxAge > 0 {
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(h.config.MaxAge))
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method == "OPTIONS" {
+		w.Header().Set("Allow", corsMethods)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	h.next.ServeHTTP(w, r)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORS(t *testing.T) {
+	reached := false
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	})
+
+	tests := []struct {
+		config      CORSConfig
+		method      string
+		origin      string
+		preflight   bool
+		status      int
+		allowOrigin string
+		reached     bool
+	}{
+		{defaultCORS, "POST", "https://a.example", false, http.StatusOK, "*", true},
+		{defaultCORS, "OPTIONS", "https://a.example", true, http.StatusNoContent, "*", false},
+		{defaultCORS, "GET", "", false, http.StatusOK, "", true},
+		{CORSConfig{Origins: []string{"https://a.example"}}, "GET", "https://a.example", false, http.StatusOK, "https://a.example", true},
+		{CORSConfig{Origins: []string{"https://a.example"}}, "GET", "https://b.example", false, http.StatusOK, "", true},
+		{CORSConfig{Origins: []string{"https://a.example"}}, "OPTIONS", "https://b.example", true, http.StatusForbidden, "", false},
+		{CORSConfig{Origins: []string{"*"}, Credentials: true}, "GET", "https://b.example", false, http.StatusOK, "https://b.example", true},
+		{defaultCORS, "OPTIONS", "", false, http.StatusNoContent, "", false},
+	}
+
+	for _, test := range tests {
+		reached = false
+		req := httptest.NewRequest(test.method, "/tv/0/status", nil)
+		if test.origin != "" {
+			req.Header.Set("Origin", test.origin)
+		}
+		if test.preflight {
+			req.Header.Set("Access-Control-Request-Method", "POST")
+		}
+		w := httptest.NewRecorder()
+		withCORS(test.config, next).ServeHTTP(w, req)
+
+		if w.Code != test.status {
+			t.Errorf("Got %d instead of %d for %s from %q!", w.Code, test.status, test.method, test.origin)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != test.allowOrigin {
+			t.Errorf("Got allowed origin %q instead of %q for %s from %q!", got, test.allowOrigin, test.method, test.origin)
+		}
+		if reached != test.reached {
+			t.Errorf("Handler reached: %v, expected %v, for %s from %q!", reached, test.reached, test.method, test.origin)
+		}
+		if test.config.Credentials && w.Header().Get("Access-Control-Allow-Credentials") != "true" {
+			t.Errorf("Credentials were not allowed for %q!", test.origin)
+		}
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	defer useFakeTVs(&fakeTV{})()
+
+	w := httptest.NewRecorder()
+	newTVServer().ServeHTTP(w, httptest.NewRequest("GET", "/tv/fake0/power", nil))
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != "POST" {
+		t.Errorf("Got %d, Allow %q instead of 405, Allow POST!", w.Code, w.Header().Get("Allow"))
+	}
+}
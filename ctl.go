@@ -0,0 +1,298 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DHowett/avantgarde/tv"
+)
+
+// ctlCommand is `avantgarde ctl`, which drives TVs through a running
+// server's JSON API.
+type ctlCommand struct {
+	Server string `short:"s" long:"server" env:"AVANTGARDE_SERVER" description:"server to talk to" default:"http://localhost:5456"`
+	Token  string `short:"t" long:"token" env:"AVANTGARDE_TOKEN" description:"API token"`
+	JSON   bool   `long:"json" description:"print status and events as JSON"`
+
+	Args struct {
+		TV      string   `positional-arg-name:"tv" description:"the TV's name or number" required:"yes"`
+		Command string   `positional-arg-name:"command" description:"power, volume, mute, unmute, input, channel, status, watch, raw, or any attribute" required:"yes"`
+		Values  []string `positional-arg-name:"value"`
+	} `positional-args:"yes"`
+
+	// out receives what the command prints; it is os.Stdout if nil.
+	out io.Writer
+}
+
+func parseSwitch(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "on", "1", "true", "yes":
+		return true, nil
+	case "off", "0", "false", "no":
+		return false, nil
+	}
+	return false, fmt.Errorf("%q is neither on nor off", s)
+}
+
+// parseLevel reads an absolute level, like 15, or a step, like +3 or -3.
+// The words up and down step by one, and min and max stand for 0 and 100.
+func parseLevel(op *apiOp, s string) error {
+	switch s {
+	case "up":
+		s = "+1"
+	case "down":
+		s = "-1"
+	case "min":
+		s = "0"
+	case "max":
+		s = "100"
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("%q is not a number", s)
+	}
+	switch {
+	case strings.HasPrefix(s, "+"):
+		op.Operator = tv.Increment.String()
+	case strings.HasPrefix(s, "-"):
+		op.Operator, n = tv.Decrement.String(), -n
+	}
+	op.Value, _ = json.Marshal(n)
+	return nil
+}
+
+// ctlOp turns a command line, like `volume +3` or `input hdmi 2`, into an
+// operation for the JSON API.
+func ctlOp(command string, values []string) (*apiOp, error) {
+	switch command {
+	case "mute":
+		if len(values) == 0 {
+			values = []string{"on"}
+		}
+	case "unmute":
+		values = append([]string{"off"}, values...)
+		command = tv.Mute.String()
+	case "channel":
+		command = tv.Tuning.String()
+	}
+
+	attr, err := tv.ParseAttribute(command)
+	if err != nil {
+		return nil, fmt.Errorf("unknown command %q", command)
+	}
+	op := &apiOp{Attribute: attr.String(), Operator: tv.Set.String()}
+
+	switch attr {
+	case tv.Input:
+		if len(values) != 2 {
+			return nil, errors.New("input takes a connection and a number, like `input hdmi 2`")
+		}
+		n, err := strconv.Atoi(values[1])
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an input number", values[1])
+		}
+		op.Value, _ = json.Marshal(apiInput{values[0], n})
+		return op, nil
+	case tv.Raw:
+		if len(values) == 0 {
+			return nil, errors.New("raw takes a command")
+		}
+		op.Value, _ = json.Marshal(strings.Join(values, " "))
+		return op, nil
+	}
+
+	// Negative numbers look like flags, so "down 3" says "-3" as well.
+	if len(values) == 2 && (values[0] == "up" || values[0] == "down") {
+		sign := "+"
+		if values[0] == "down" {
+			sign = "-"
+		}
+		values = []string{sign + values[1]}
+	}
+	if len(values) != 1 {
+		return nil, fmt.Errorf("%s takes one value", command)
+	}
+	switch attr {
+	case tv.Power, tv.Mute, tv.Screen, tv.OSD, tv.Lock, tv.PIP:
+		if values[0] == "toggle" {
+			op.Operator = tv.Toggle.String()
+			return op, nil
+		}
+		on, err := parseSwitch(values[0])
+		if err != nil {
+			return nil, err
+		}
+		op.Value, _ = json.Marshal(on)
+	case tv.Tuning:
+		op.Value, _ = json.Marshal(values[0])
+	default:
+		if err := parseLevel(op, values[0]); err != nil {
+			return nil, err
+		}
+	}
+	return op, nil
+}
+
+func (c *ctlCommand) request(client *http.Client, method, path string, body interface{}) (*http.Response, error) {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, strings.TrimSuffix(c.Server, "/")+path, r)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		var e apiErrorResponse
+		if json.NewDecoder(resp.Body).Decode(&e) == nil && e.Error.Code != "" {
+			return nil, fmt.Errorf("%s: %s", e.Error.Code, e.Error.Message)
+		}
+		return nil, errors.New(resp.Status)
+	}
+	return resp, nil
+}
+
+func (c *ctlCommand) Execute(args []string) error {
+	if c.out == nil {
+		c.out = os.Stdout
+	}
+	id := url.PathEscape(c.Args.TV)
+
+	switch c.Args.Command {
+	case "status":
+		return c.status(id)
+	case "watch":
+		return c.watch()
+	}
+
+	op, err := ctlOp(c.Args.Command, c.Args.Values)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := c.request(client, "POST", "/api/v1/tv/"+id+"/op", op)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Only raw commands answer with anything.
+	if resp.StatusCode == http.StatusOK {
+		var reply apiRawReply
+		if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+			return err
+		}
+		if reply.Reply != "" {
+			fmt.Fprintln(c.out, reply.Reply)
+		}
+	}
+	return nil
+}
+
+func (c *ctlCommand) status(id string) error {
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := c.request(client, "GET", "/api/v1/tv/"+id+"/state", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var state tv.State
+	if err := json.NewDecoder(resp.Body).Decode(&state); err != nil {
+		return err
+	}
+	if c.JSON {
+		return json.NewEncoder(c.out).Encode(&state)
+	}
+	fmt.Fprint(c.out, formatState(&state))
+	return nil
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
+
+func formatChannel(ch tv.Channel) string {
+	switch ch := ch.(type) {
+	case nil:
+		return "none"
+	case tv.DigitalChannel:
+		return fmt.Sprintf("%d.%d", ch.Ch, ch.Sub)
+	case map[string]interface{}:
+		// Digital channels come back from JSON as objects.
+		return fmt.Sprintf("%v.%v", ch["Ch"], ch["Sub"])
+	}
+	return fmt.Sprint(ch)
+}
+
+// formatState describes a state one attribute to a line.
+func formatState(state *tv.State) string {
+	return fmt.Sprintf("power:   %s\nvolume:  %d\nmute:    %s\nscreen:  %s\nchannel: %s\ninput:   %s %d\n",
+		onOff(state.Power), state.Volume, onOff(state.Mute), onOff(state.Screen),
+		formatChannel(state.Channel), connectionNames[state.Input.Connection], state.Input.Number)
+}
+
+// watch prints the TV's state whenever it changes, until the server goes
+// away.
+func (c *ctlCommand) watch() error {
+	resp, err := c.request(&http.Client{}, "GET", "/events", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data := strings.TrimPrefix(scanner.Text(), "data: ")
+		if data == scanner.Text() {
+			continue
+		}
+		var ev stateEvent
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			return err
+		}
+		if ev.Name != c.Args.TV && strconv.Itoa(ev.ID) != c.Args.TV {
+			continue
+		}
+		switch {
+		case c.JSON:
+			fmt.Fprintln(c.out, data)
+		case ev.State == nil:
+			fmt.Fprintf(c.out, "%s  %s: %s\n", time.Now().Format("15:04:05"), ev.Connection, ev.Error)
+		default:
+			fmt.Fprintf(c.out, "%s  %s\n", time.Now().Format("15:04:05"), strings.Join(strings.Fields(formatState(ev.State)), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return errors.New("the server closed the stream")
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DHowett/avantgarde/tv"
+)
+
+func TestCtlOp(t *testing.T) {
+	tests := []struct {
+		command string
+		values  []string
+		op      string
+	}{
+		{"power", []string{"on"}, `power set true`},
+		{"power", []string{"toggle"}, `power toggle `},
+		{"volume", []string{"15"}, `volume set 15`},
+		{"volume", []string{"+3"}, `volume increment 3`},
+		{"volume", []string{"-3"}, `volume decrement 3`},
+		{"volume", []string{"down", "3"}, `volume decrement 3`},
+		{"volume", []string{"up"}, `volume increment 1`},
+		{"volume", []string{"max"}, `volume set 100`},
+		{"mute", nil, `mute set true`},
+		{"unmute", nil, `mute set false`},
+		{"input", []string{"hdmi", "2"}, `input set {"connection":"hdmi","number":2}`},
+		{"channel", []string{"7.1"}, `tuning set "7.1"`},
+		{"brightness", []string{"40"}, `brightness set 40`},
+		{"raw", []string{"ka", "01", "01"}, `raw set "ka 01 01"`},
+	}
+	for _, test := range tests {
+		op, err := ctlOp(test.command, test.values)
+		if err != nil {
+			t.Errorf("Failed to parse %s %v: %v", test.command, test.values, err)
+			continue
+		}
+		if got := fmt.Sprintf("%s %s %s", op.Attribute, op.Operator, string(op.Value)); got != test.op {
+			t.Errorf("Got %q instead of %q for %s %v!", got, test.op, test.command, test.values)
+		}
+	}
+
+	for _, bad := range [][]string{
+		{"power", "maybe"},
+		{"volume", "loud"},
+		{"volume"},
+		{"input", "hdmi"},
+		{"unmute", "now"},
+		{"warp", "9"},
+	} {
+		if op, err := ctlOp(bad[0], bad[1:]); err == nil {
+			t.Errorf("Got %+v instead of an error for %v!", op, bad)
+		}
+	}
+}
+
+func TestCtl(t *testing.T) {
+	fake := &fakeTV{}
+	defer useFakeTVs(fake)()
+	srv := httptest.NewServer(&apiServer{})
+	defer srv.Close()
+
+	ctl := func(command string, values ...string) (string, error) {
+		out := &bytes.Buffer{}
+		c := &ctlCommand{Server: srv.URL, out: out}
+		c.Args.TV, c.Args.Command, c.Args.Values = "fake0", command, values
+		err := c.Execute(nil)
+		return out.String(), err
+	}
+
+	if _, err := ctl("volume", "+3"); err != nil {
+		t.Errorf("Failed to step the volume: %v", err)
+	}
+	if len(fake.ops) != 3 || fmt.Sprint(*fake.ops[2]) != fmt.Sprint(tv.Op{tv.Volume, tv.Increment, 1}) {
+		t.Errorf("Got %v instead of three volume increments!", fake.ops)
+	}
+
+	out, err := ctl("status")
+	if err != nil || !strings.Contains(out, "power:   on") || !strings.Contains(out, "volume:  15") {
+		t.Errorf("Got %q, %v instead of the TV's state!", out, err)
+	}
+
+	fake.err = fmt.Errorf("fake: %w", tv.ErrUnsupported)
+	if _, err := ctl("power", "off"); err == nil || !strings.HasPrefix(err.Error(), codeUnsupported) {
+		t.Errorf("Got %v instead of an unsupported error!", err)
+	}
+}
+
+// steppingTV moves its volume by one for every Increment or Decrement, as
+// drivers do whatever step they are asked for.
+type steppingTV struct {
+	*fakeTV
+	volume int
+}
+
+func (s *steppingTV) Do(op *tv.Op) error {
+	switch op.Operator {
+	case tv.Set:
+		s.volume = op.Value.(int)
+	case tv.Increment:
+		s.volume++
+	case tv.Decrement:
+		s.volume--
+	}
+	return nil
+}
+
+func (s *steppingTV) State() (*tv.State, error) {
+	return &tv.State{Volume: s.volume}, nil
+}
+
+func TestCtlVolumeSteps(t *testing.T) {
+	stepping := &steppingTV{&fakeTV{}, 15}
+	defer useFakeTVs(stepping)()
+	srv := httptest.NewServer(&apiServer{})
+	defer srv.Close()
+
+	for _, test := range []struct {
+		values []string
+		volume int
+	}{
+		{[]string{"+3"}, 18},
+		{[]string{"down", "5"}, 13},
+		{[]string{"up"}, 14},
+	} {
+		c := &ctlCommand{Server: srv.URL, out: io.Discard}
+		c.Args.TV, c.Args.Command, c.Args.Values = "fake0", "volume", test.values
+		if err := c.Execute(nil); err != nil {
+			t.Errorf("Failed to step the volume by %v: %v", test.values, err)
+		}
+		if state, _ := stepping.State(); state.Volume != test.volume {
+			t.Errorf("Got volume %d instead of %d after %v!", state.Volume, test.volume, test.values)
+		}
+	}
+
+	c := &ctlCommand{Server: srv.URL, out: io.Discard}
+	c.Args.TV, c.Args.Command, c.Args.Values = "fake0", "volume", []string{"+1000"}
+	if err := c.Execute(nil); err == nil || !strings.HasPrefix(err.Error(), codeInvalidValue) {
+		t.Errorf("Got %v instead of too large a step being refused!", err)
+	}
+}
+
+func TestCtlAuth(t *testing.T) {
+	defer useFakeTVs(&fakeTV{}, &fakeTV{})()
+	defer useTestTokens(t)()
+	srv := httptest.NewServer(&apiServer{})
+	defer srv.Close()
+
+	c := &ctlCommand{Server: srv.URL, Token: "bar-secret", out: &bytes.Buffer{}}
+	c.Args.TV, c.Args.Command, c.Args.Values = "fake1", "power", []string{"on"}
+	if err := c.Execute(nil); err == nil || !strings.HasPrefix(err.Error(), codeForbidden) {
+		t.Errorf("Got %v instead of a forbidden error!", err)
+	}
+
+	c.Args.Command, c.Args.Values = "volume", []string{"10"}
+	if err := c.Execute(nil); err != nil {
+		t.Errorf("Failed with an allowed token: %v", err)
+	}
+}
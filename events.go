@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/DHowett/avantgarde/tv"
+)
+
+// stateEvent reports the state of one TV, as it is sent on /events.
+type stateEvent struct {
+	ID         int    `json:"id"`
+	Name       string `json:"name,omitempty"`
+	Connection string `json:"connection"`
+	// State is nil, and Error set, when the TV could not be asked.
+	State *tv.State `json:"state,omitempty"`
+	// Input repeats State.Input with the connection spelled out.
+	Input *apiInput `json:"input,omitempty"`
+	Error string    `json:"error,omitempty"`
+}
+
+// stateHub polls every TV for its state and passes changes on to its
+// subscribers. TVs are polled independently, so that one slow TV does not
+// hold the others up, and again right after they are sent a command.
+type stateHub struct {
+	interval time.Duration
+	refresh  []chan struct{}
+
+	mu   sync.Mutex
+	last []*stateEvent
+	subs map[chan *stateEvent]bool
+}
+
+// hub is nil until main starts it.
+var hub *stateHub
+
+func newStateHub(interval time.Duration) *stateHub {
+	h := &stateHub{
+		interval: interval,
+		refresh:  make([]chan struct{}, len(tvs)),
+		last:     make([]*stateEvent, len(tvs)),
+		subs:     make(map[chan *stateEvent]bool),
+	}
+	for i := range h.refresh {
+		h.refresh[i] = make(chan struct{}, 1)
+	}
+	return h
+}
+
+// start polls every TV until quit is closed.
+func (h *stateHub) start(quit <-chan struct{}) {
+	for i := range tvs {
+		go h.watch(i, quit)
+	}
+}
+
+func (h *stateHub) watch(id int, quit <-chan struct{}) {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+	for {
+		h.poll(id)
+		select {
+		case <-quit:
+			return
+		case <-ticker.C:
+		case <-h.refresh[id]:
+		}
+	}
+}
+
+func (h *stateHub) poll(id int) {
+	ev := &stateEvent{ID: id, Name: tvConfigs[id].V.Name, Connection: connectionState(id)}
+	state, err := tvs[id].State()
+	if err != nil {
+		ev.Error = err.Error()
+	} else {
+		ev.State = state
+		ev.Input = &apiInput{connectionNames[state.Input.Connection], state.Input.Number}
+	}
+	h.publish(ev)
+}
+
+// publish passes an event on to every subscriber, unless it is the same as
+// the last one for its TV. Subscribers that have fallen too far behind are
+// dropped; they can subscribe again for a fresh snapshot.
+func (h *stateHub) publish(ev *stateEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if last := h.last[ev.ID]; last != nil {
+		a, _ := json.Marshal(last)
+		b, _ := json.Marshal(ev)
+		if string(a) == string(b) {
+			return
+		}
+	}
+	h.last[ev.ID] = ev
+
+	for ch := range h.subs {
+		select {
+		case ch <- ev:
+		default:
+			delete(h.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// changed asks for a TV to be polled again soon, after it has been sent a
+// command. It does nothing if the hub is not running.
+func (h *stateHub) changed(id int) {
+	if h == nil {
+		return
+	}
+	select {
+	case h.refresh[id] <- struct{}{}:
+	default:
+	}
+}
+
+// subscribe returns a channel that receives the last known state of every
+// TV, then each change as it happens. The channel is closed if the
+// subscriber falls behind.
+func (h *stateHub) subscribe() chan *stateEvent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch := make(chan *stateEvent, len(h.last)+16)
+	for _, ev := range h.last {
+		if ev != nil {
+			ch <- ev
+		}
+	}
+	h.subs[ch] = true
+	return ch
+}
+
+func (h *stateHub) unsubscribe(ch chan *stateEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.subs[ch] {
+		delete(h.subs, ch)
+		close(ch)
+	}
+}
+
+// serveEvents streams state changes as server-sent events, each an "event:
+// state" whose data is a stateEvent.
+func serveEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		w.Header().Set("Allow", "GET")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	g, ok := auth.authenticate(w, r, plainFailure(w))
+	if !ok {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if hub == nil || !ok {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	ch := hub.subscribe()
+	defer hub.unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	// Comments keep proxies from closing an idle stream.
+	keepalive := time.NewTicker(30 * time.Second)
+	defer keepalive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+		case ev, ok := <-ch:
+			if !ok {
+				return
+			}
+			if g != nil && !g.allowsTV(ev.ID) {
+				continue
+			}
+			data, _ := json.Marshal(ev)
+			fmt.Fprintf(w, "event: state\ndata: %s\n\n", data)
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useTestHub installs a hub that has polled every TV once, but does not
+// poll them again on its own.
+func useTestHub() func() {
+	hub = newStateHub(time.Hour)
+	for i := range tvs {
+		hub.poll(i)
+	}
+	return func() { hub = nil }
+}
+
+func TestStateHub(t *testing.T) {
+	fakes := []*fakeTV{{}, {}}
+	defer useFakeTVs(fakes[0], fakes[1])()
+
+	h := newStateHub(time.Hour)
+	h.poll(0)
+	ch := h.subscribe()
+	defer h.unsubscribe(ch)
+
+	// The snapshot holds only the TV that has been polled.
+	if ev := <-ch; ev.ID != 0 || ev.State == nil || ev.Input == nil || ev.Input.Connection != "coaxial" {
+		t.Errorf("Got %+v instead of the state of TV 0!", ev)
+	}
+
+	// An unchanged state is not sent again.
+	h.poll(0)
+	h.poll(1)
+	if ev := <-ch; ev.ID != 1 {
+		t.Errorf("Got an event for TV %d instead of TV 1!", ev.ID)
+	}
+
+	fakes[0].err = errTest
+	h.poll(0)
+	if ev := <-ch; ev.ID != 0 || ev.State != nil || ev.Error != errTest.Error() {
+		t.Errorf("Got %+v instead of an error for TV 0!", ev)
+	}
+}
+
+var errTest = errors.New("test error")
+
+func TestEvents(t *testing.T) {
+	defer useFakeTVs(&fakeTV{}, &fakeTV{})()
+	defer useTestTokens(t)()
+	defer useTestHub()()
+
+	srv := httptest.NewServer(http.HandlerFunc(serveEvents))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", srv.URL, nil)
+	req.Header.Set("Authorization", "Bearer bar-secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Got content type %q instead of an event stream!", ct)
+	}
+
+	// The token only grants TV 1, so only its state comes through.
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var ev stateEvent
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &ev); err != nil {
+			t.Fatalf("Failed to parse %q: %v", line, err)
+		}
+		if ev.ID != 1 || ev.Name != "fake1" || ev.State == nil || ev.State.Volume != 15 {
+			t.Errorf("Got %+v instead of the state of fake1!", ev)
+		}
+		return
+	}
+	t.Errorf("The stream ended without an event: %v", scanner.Err())
+}
+
+func TestEventsUnauthorized(t *testing.T) {
+	defer useFakeTVs(&fakeTV{}, &fakeTV{})()
+	defer useTestTokens(t)()
+	defer useTestHub()()
+
+	w := httptest.NewRecorder()
+	serveEvents(w, httptest.NewRequest("GET", "/events", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Got %d instead of %d without a token!", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestUI(t *testing.T) {
+	ui := newUIHandler()
+
+	w := httptest.NewRecorder()
+	ui.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "&#34;hdmi&#34;") {
+		t.Errorf("Got %d instead of a page naming the connections: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	ui.ServeHTTP(w, httptest.NewRequest("GET", "/ui/app.js", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `request("events")`) {
+		t.Errorf("Got %d instead of the script!", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	ui.ServeHTTP(w, httptest.NewRequest("GET", "/nowhere", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Got %d instead of %d for an unknown page!", w.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/DHowett/avantgarde/tv"
+)
+
+// Exit statuses of `avantgarde exec`, so that scripts can tell failures
+// apart. Anything not listed exits with 1.
+const (
+	exitUsage        = 2
+	exitUnsupported  = 3
+	exitTimeout      = 4
+	exitDisconnected = 5
+)
+
+// exitError carries the status a subcommand wants to exit with.
+type exitError struct {
+	status int
+	err    error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// exitStatus picks the status for an error returned by a TV.
+func exitStatus(err error) int {
+	switch {
+	case errors.Is(err, tv.ErrInvalidValue):
+		return exitUsage
+	case errors.Is(err, tv.ErrUnsupported):
+		return exitUnsupported
+	case errors.Is(err, tv.ErrTimeout):
+		return exitTimeout
+	case errors.Is(err, tv.ErrDisconnected):
+		return exitDisconnected
+	}
+	return 1
+}
+
+// execCommand is `avantgarde exec`, which drives one TV directly, without a
+// configuration file or a server, e.g. to try out a newly installed set.
+type execCommand struct {
+	Model   string        `short:"m" long:"model" description:"the TV's model" required:"yes"`
+	Port    string        `short:"p" long:"port" description:"serial port the TV is connected to"`
+	Baud    int           `long:"baud" description:"serial port speed" default:"9600"`
+	SetID   string        `long:"set-id" description:"the TV's set ID, for models that have one"`
+	Options []string      `short:"o" long:"option" description:"model setting as it would appear in config.yml, like address=10.0.0.5; may be repeated"`
+	Timeout time.Duration `long:"timeout" description:"how long to wait for each operation" default:"10s"`
+	JSON    bool          `long:"json" description:"print status as JSON"`
+
+	Args struct {
+		Ops []string `positional-arg-name:"op" description:"operations as for ctl, like \"power on\" or \"status\"; separate several with commas" required:"yes"`
+	} `positional-args:"yes"`
+
+	out io.Writer
+	// rwc replaces the serial port in tests.
+	rwc io.ReadWriteCloser
+}
+
+// modelConfig builds a model's configuration from key=value settings, as
+// if they had been written in config.yml.
+func modelConfig(model string, settings []string) (tv.Config, error) {
+	known := false
+	for _, m := range tv.Models() {
+		known = known || m == model
+	}
+	if !known {
+		return nil, fmt.Errorf("unknown model %q; models are %s", model, strings.Join(tv.Models(), ", "))
+	}
+
+	values := map[string]interface{}{}
+	for _, s := range settings {
+		kv := strings.SplitN(s, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("setting %q is not key=value", s)
+		}
+		// Let YAML decide whether the value is a number, a boolean or a
+		// string, as it would in config.yml.
+		var v interface{}
+		if err := yaml.Unmarshal([]byte(kv[1]), &v); err != nil {
+			return nil, fmt.Errorf("setting %q: %v", s, err)
+		}
+		values[kv[0]] = v
+	}
+	b, err := yaml.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	cfg := tv.NewConfig(model)
+	if err := yaml.UnmarshalStrict(b, cfg); err != nil {
+		return nil, fmt.Errorf("%s: %v", model, err)
+	}
+	return cfg, nil
+}
+
+// splitOps breaks the arguments into operations at commas, which may stand
+// alone or end a word: "power on, volume 10" and "power on , volume 10"
+// are the same.
+func splitOps(args []string) [][]string {
+	var ops [][]string
+	var op []string
+	for _, a := range args {
+		end := strings.HasSuffix(a, ",")
+		if a = strings.TrimSuffix(a, ","); a != "" {
+			op = append(op, a)
+		}
+		if end && len(op) > 0 {
+			ops = append(ops, op)
+			op = nil
+		}
+	}
+	if len(op) > 0 {
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+func (c *execCommand) open() (io.ReadWriteCloser, error) {
+	if c.rwc != nil || c.Port == "" {
+		return c.rwc, nil
+	}
+	return Port{c.Port, uint(c.Baud)}.open()
+}
+
+func (c *execCommand) Execute(args []string) error {
+	if c.out == nil {
+		c.out = os.Stdout
+	}
+
+	settings := c.Options
+	if c.SetID != "" {
+		settings = append(settings, "setid="+c.SetID)
+	}
+	cfg, err := modelConfig(c.Model, settings)
+	if err != nil {
+		return &exitError{exitUsage, err}
+	}
+
+	// Check every operation before touching the TV.
+	ops := splitOps(c.Args.Ops)
+	parsed := make([]*tv.Op, len(ops))
+	for i, op := range ops {
+		if op[0] == "status" {
+			continue
+		}
+		a, err := ctlOp(op[0], op[1:])
+		if err == nil {
+			parsed[i], err = a.toOp()
+		}
+		if err != nil {
+			return &exitError{exitUsage, err}
+		}
+	}
+
+	rwc, err := c.open()
+	if err != nil {
+		return &exitError{exitDisconnected, err}
+	}
+	if rwc != nil {
+		defer rwc.Close()
+	}
+	t, err := tv.New(c.Model, rwc, cfg)
+	if err != nil {
+		return &exitError{exitUsage, err}
+	}
+
+	for i, op := range parsed {
+		if op != nil {
+			if err := checkSupported(t, op); err != nil {
+				return &exitError{exitStatus(err), fmt.Errorf("%s: %w", strings.Join(ops[i], " "), err)}
+			}
+		}
+	}
+	for i, op := range parsed {
+		if err := c.do(t, op); err != nil {
+			return &exitError{exitStatus(err), fmt.Errorf("%s: %w", strings.Join(ops[i], " "), err)}
+		}
+	}
+	return nil
+}
+
+// do performs one operation, or prints the state if op is nil, giving up
+// after the timeout.
+func (c *execCommand) do(t tv.TV, op *tv.Op) error {
+	var out string
+	done := make(chan error, 1)
+	go func() {
+		switch {
+		case op == nil:
+			state, err := t.State()
+			if err == nil {
+				if c.JSON {
+					b, _ := json.Marshal(state)
+					out = string(b) + "\n"
+				} else {
+					out = formatState(state)
+				}
+			}
+			done <- err
+		case op.Attribute == tv.Raw:
+			if rr, ok := t.(tv.RawReplier); ok {
+				reply, err := rr.DoRaw(op.Value.([]byte))
+				if len(reply) > 0 {
+					out = fmt.Sprintf("%q\n", reply)
+				}
+				done <- err
+				return
+			}
+			fallthrough
+		default:
+			done <- doOp(t, op)
+		}
+	}()
+
+	select {
+	case err := <-done:
+		fmt.Fprint(c.out, out)
+		return err
+	case <-time.After(c.Timeout):
+		return tv.ErrTimeout
+	}
+}
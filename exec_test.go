@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DHowett/avantgarde/tv"
+	"github.com/DHowett/avantgarde/tv/lg"
+	"github.com/DHowett/avantgarde/tv/sharp"
+)
+
+func TestModelConfig(t *testing.T) {
+	cfg, err := modelConfig("lg", []string{"setid=3"})
+	if err != nil {
+		t.Fatalf("Failed to configure an LG: %v", err)
+	}
+	if c := cfg.(*lg.Config); c.SetID != 3 {
+		t.Errorf("Got set ID %d instead of 3!", c.SetID)
+	}
+
+	cfg, err = modelConfig("sharp", []string{"address=10.0.0.5:10002"})
+	if err != nil {
+		t.Fatalf("Failed to configure a Sharp: %v", err)
+	}
+	if c := cfg.(*sharp.Config); c.Address != "10.0.0.5:10002" {
+		t.Errorf("Got address %q instead of 10.0.0.5:10002!", c.Address)
+	}
+
+	for _, bad := range [][]string{{"nope"}, {"sharp", "setid=1"}, {"lg", "setid"}} {
+		if _, err := modelConfig(bad[0], bad[1:]); err == nil {
+			t.Errorf("Accepted %v!", bad)
+		}
+	}
+}
+
+func TestSplitOps(t *testing.T) {
+	got := splitOps(strings.Fields("power on, volume 10 , status"))
+	expect := [][]string{{"power", "on"}, {"volume", "10"}, {"status"}}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("Got %q instead of %q!", got, expect)
+	}
+}
+
+// hangingTV never finishes an operation.
+type hangingTV struct{ fakeTV }
+
+func (h *hangingTV) Do(op *tv.Op) error {
+	select {}
+}
+
+func TestExec(t *testing.T) {
+	run := func(ops ...string) (string, error) {
+		out := &bytes.Buffer{}
+		c := &execCommand{Model: "fake", Timeout: time.Second, out: out}
+		c.Args.Ops = ops
+		err := c.Execute(nil)
+		return out.String(), err
+	}
+
+	out, err := run("power", "on,", "status")
+	if err != nil {
+		t.Fatalf("Failed: %v", err)
+	}
+	if !strings.Contains(out, "volume:  15") {
+		t.Errorf("Got %q instead of the state!", out)
+	}
+
+	var exit *exitError
+	if _, err := run("volume", "loud"); !errors.As(err, &exit) || exit.status != exitUsage {
+		t.Errorf("Got %v instead of a usage error!", err)
+	}
+	if _, err := run("power", "toggle"); !errors.As(err, &exit) || exit.status != exitUnsupported {
+		t.Errorf("Got %v instead of power toggling being unsupported!", err)
+	}
+
+	for _, test := range []struct {
+		err    error
+		status int
+	}{
+		{fmt.Errorf("fake: %w", tv.ErrUnsupported), exitUnsupported},
+		{fmt.Errorf("fake: %w", tv.ErrDisconnected), exitDisconnected},
+		{errors.New("fake: broken"), 1},
+	} {
+		if status := exitStatus(test.err); status != test.status {
+			t.Errorf("Got %d instead of %d for %v!", status, test.status, test.err)
+		}
+	}
+}
+
+func TestExecTimeout(t *testing.T) {
+	c := &execCommand{Timeout: 10 * time.Millisecond, out: io.Discard}
+	if err := c.do(&hangingTV{}, &tv.Op{tv.Power, tv.Set, true}); !errors.Is(err, tv.ErrTimeout) {
+		t.Errorf("Got %v instead of a timeout!", err)
+	}
+}
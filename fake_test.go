@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/DHowett/avantgarde/tv"
+)
+
+type fakeConfig struct{}
+
+func (c fakeConfig) ModelSpecificRepresentation() interface{} {
+	return c
+}
+
+// fakeModel creates TVs that record the operations they are asked to do.
+type fakeModel struct{}
+
+func (m *fakeModel) Initialize(rwc io.ReadWriteCloser, c tv.Config) (tv.TV, error) {
+	return &fakeTV{}, nil
+}
+
+func (m *fakeModel) NewConfig() tv.Config {
+	return &fakeConfig{}
+}
+
+type fakeTV struct {
+	mu    sync.Mutex
+	ops   []*tv.Op
+	err   error
+	reply []byte
+}
+
+func (f *fakeTV) Do(op *tv.Op) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ops = append(f.ops, op)
+	return f.err
+}
+
+func (f *fakeTV) DoRaw(cmd []byte) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ops = append(f.ops, &tv.Op{tv.Raw, tv.Set, cmd})
+	return f.reply, f.err
+}
+
+func (f *fakeTV) State() (*tv.State, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return &tv.State{Power: true, Volume: 15}, f.err
+}
+
+func (f *fakeTV) Capabilities() []tv.Capability {
+	return []tv.Capability{
+		{Attribute: tv.Power, Operators: []tv.Operator{tv.Set}},
+		{Attribute: tv.Volume, Operators: []tv.Operator{tv.Set, tv.Increment, tv.Decrement}, Min: 0, Max: 100},
+		{Attribute: tv.Mute, Operators: []tv.Operator{tv.Set, tv.Toggle}},
+		{Attribute: tv.Input, Operators: []tv.Operator{tv.Set}, Inputs: []tv.InputNumber{{tv.HDMI, 1}}},
+	}
+}
+
+// panicTV is a TV whose driver panics when asked to do anything.
+type panicTV struct {
+	fakeTV
+}
+
+func (p *panicTV) Do(op *tv.Op) error {
+	panic("fake: broken driver")
+}
+
+func (p *panicTV) State() (*tv.State, error) {
+	panic("fake: broken driver")
+}
+
+// fakeSwitcher is a matrix switcher whose routes fail with its err.
+type fakeSwitcher struct {
+	fakeTV
+}
+
+func (f *fakeSwitcher) Route(input, output int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.err
+}
+
+func (f *fakeSwitcher) Routes() (map[int]int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return map[int]int{1: 1}, f.err
+}
+
+// useFakeTVs installs TVs for a test, naming each "fake" followed by its
+// number, and returns a function that removes them again.
+func useFakeTVs(fakes ...tv.TV) func() {
+	tvs = fakes
+	tvConfigs = make([]TVConfig, len(fakes))
+	tvNames = map[string]int{}
+	for i := range fakes {
+		tvConfigs[i].V.Name = fmt.Sprintf("fake%d", i)
+		tvConfigs[i].V.Model = "fake"
+		tvNames[tvConfigs[i].V.Name] = i
+	}
+	return func() {
+		tvs, tvConfigs, tvNames = nil, nil, map[string]int{}
+	}
+}
+
+func init() {
+	tv.RegisterModel("fake", &fakeModel{})
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/DHowett/avantgarde/tv"
+)
+
+// GroupConfig lists the TVs in a group in config.yml, either plainly or
+// along with a TV that reaches all of them at once:
+//
+//	groups:
+//	  bar: [bar-1, bar-2, bar-3]
+//	  wall:
+//	    members: [wall-1, wall-2, wall-3, wall-4]
+//	    broadcast: wall-all
+type GroupConfig struct {
+	Members []string
+	// Broadcast names a TV whose commands reach every member, such as an
+	// LG with set ID 0 on the port the members are daisy-chained from.
+	// Commands are sent to it once instead of to each member.
+	Broadcast string
+}
+
+func (c *GroupConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&c.Members); err == nil {
+		return nil
+	}
+	type plain GroupConfig
+	return unmarshal((*plain)(c))
+}
+
+type group struct {
+	name    string
+	members []int
+	// broadcast is the TV that reaches every member, or -1.
+	broadcast int
+}
+
+// groups holds the configured groups by name.
+var groups = map[string]*group{}
+
+func newGroup(name string, c GroupConfig) (*group, error) {
+	if len(c.Members) == 0 {
+		return nil, fmt.Errorf("group `%s` has no members", name)
+	}
+	gr := &group{name: name, broadcast: -1}
+	seen := map[int]bool{}
+	for _, member := range c.Members {
+		id, ok := findTV(member)
+		if !ok {
+			return nil, fmt.Errorf("group `%s`: no such TV `%s`", name, member)
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("group `%s` lists `%s` more than once", name, member)
+		}
+		seen[id] = true
+		gr.members = append(gr.members, id)
+	}
+	if c.Broadcast != "" {
+		id, ok := findTV(c.Broadcast)
+		if !ok {
+			return nil, fmt.Errorf("group `%s`: no such TV `%s`", name, c.Broadcast)
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("group `%s` broadcasts through its own member `%s`", name, c.Broadcast)
+		}
+		gr.broadcast = id
+	}
+	return gr, nil
+}
+
+func newGroups(cfgs map[string]GroupConfig) (map[string]*group, error) {
+	m := make(map[string]*group)
+	for name, c := range cfgs {
+		gr, err := newGroup(name, c)
+		if err != nil {
+			return nil, err
+		}
+		m[name] = gr
+	}
+	return m, nil
+}
+
+// memberNames lists the group's members as they are addressed in URLs.
+func (gr *group) memberNames() []string {
+	names := make([]string, len(gr.members))
+	for i, id := range gr.members {
+		names[i] = tvName(id)
+	}
+	return names
+}
+
+// memberResponse collects what one TV answered to a group's command.
+type memberResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (m *memberResponse) Header() http.Header {
+	return m.header
+}
+
+func (m *memberResponse) WriteHeader(status int) {
+	if m.status == 0 {
+		m.status = status
+	}
+}
+
+func (m *memberResponse) Write(b []byte) (int, error) {
+	m.WriteHeader(http.StatusOK)
+	return m.body.Write(b)
+}
+
+// groupResult reports how one TV answered a group's command: with the
+// status and body it would have answered on its own, though bodies that
+// are not JSON are given as strings.
+type groupResult struct {
+	TV     string          `json:"tv"`
+	Status int             `json:"status"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+type groupReport struct {
+	Group string `json:"group"`
+	// Status is "failed" if any TV failed, and "done" otherwise.
+	Status  string         `json:"status"`
+	Results []*groupResult `json:"results"`
+}
+
+// groupMemberState is one member's state, or why it could not be read.
+type groupMemberState struct {
+	TV    string    `json:"tv"`
+	State *tv.State `json:"state,omitempty"`
+	Error *apiError `json:"error,omitempty"`
+}
+
+type groupState struct {
+	Group string `json:"group"`
+	// Agreed holds each attribute that every member whose state could be
+	// read shows alike, written as for ctl, e.g. "hdmi 2".
+	Agreed map[string]string `json:"agreed"`
+	// Disagreed holds, for every other attribute, what each member shows.
+	Disagreed map[string]map[string]string `json:"disagreed"`
+	Members   []*groupMemberState          `json:"members"`
+}
+
+// groupServer serves every command that a TV is served at /tv/{tv}/... at
+// /group/{group}/... too, sending it to the group's members concurrently.
+type groupServer struct {
+	tvs *tvServer
+}
+
+func (gs *groupServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, ok := auth.authenticate(w, r, apiFailure(w)); !ok {
+		return
+	}
+
+	comp := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/group/"), "/", 2)
+	gr, ok := groups[comp[0]]
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, codeNotFound, fmt.Sprintf("no such group %q", comp[0]))
+		return
+	}
+	if len(comp) < 2 || comp[1] == "" {
+		writeAPIError(w, http.StatusNotFound, codeNotFound, "no such endpoint")
+		return
+	}
+	path := "/" + comp[1]
+	if path == "/state" {
+		gs.serveState(w, r, gr)
+		return
+	}
+
+	rt := gs.tvs.routeFor(path)
+	if rt == nil {
+		writeAPIError(w, http.StatusNotFound, codeNotFound, "no such endpoint")
+		return
+	}
+	if r.Method != rt.method {
+		writeAPIError(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "use "+rt.method)
+		return
+	}
+
+	// A broadcast TV reaches every member with one command, but reading
+	// state goes to each member.
+	targets, covered := gr.members, gr.members
+	broadcasting := gr.broadcast >= 0 && rt.method == "POST" && rt.attr != 0
+	if broadcasting {
+		targets = []int{gr.broadcast}
+		covered = append([]int{gr.broadcast}, gr.members...)
+	}
+	// Every member must be allowed before any is sent the command, so that
+	// a token cannot drive whichever part of a group it happens to cover.
+	for _, id := range covered {
+		if !auth.authorize(w, r, id, rt.attr, apiFailure(w)) {
+			return
+		}
+	}
+
+	// The body can only be read once, so it is parsed here for every TV.
+	if err := r.ParseForm(); err != nil {
+		writeAPIError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+	report := &groupReport{Group: gr.name, Status: stepDone, Results: make([]*groupResult, len(targets))}
+	var wg sync.WaitGroup
+	for i, id := range targets {
+		wg.Add(1)
+		go func(i, id int) {
+			defer wg.Done()
+			// Nothing else would catch a driver's panic on this goroutine
+			// before it took the whole server down.
+			defer func() {
+				if p := recover(); p != nil {
+					log.Printf("group %s: %s: panic: %v\n", gr.name, tvName(id), p)
+					report.Results[i] = &groupResult{TV: tvName(id), Status: http.StatusInternalServerError}
+				}
+			}()
+			sub := r.Clone(r.Context())
+			sub.Body = http.NoBody
+			resp := &memberResponse{header: http.Header{}}
+			gs.tvs.serveTV(resp, sub, id, path)
+			report.Results[i] = newGroupResult(id, resp)
+		}(i, id)
+	}
+	wg.Wait()
+
+	for _, res := range report.Results {
+		if res.Status >= 400 {
+			report.Status = stepFailed
+		}
+	}
+	if broadcasting {
+		for _, id := range gr.members {
+			hub.changed(id)
+		}
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+func newGroupResult(id int, resp *memberResponse) *groupResult {
+	res := &groupResult{TV: tvName(id), Status: resp.status}
+	if res.Status == 0 {
+		res.Status = http.StatusOK
+	}
+	body := bytes.TrimSpace(resp.body.Bytes())
+	if len(body) == 0 {
+		return res
+	}
+	if json.Valid(body) {
+		res.Body = body
+	} else {
+		res.Body, _ = json.Marshal(string(body))
+	}
+	return res
+}
+
+// serveState reads every member's state at once, and sets out where they
+// agree and where they do not.
+func (gs *groupServer) serveState(w http.ResponseWriter, r *http.Request, gr *group) {
+	if r.Method != "GET" {
+		writeAPIError(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "use GET")
+		return
+	}
+	for _, id := range gr.members {
+		if !auth.authorize(w, r, id, 0, apiFailure(w)) {
+			return
+		}
+	}
+
+	st := &groupState{
+		Group:     gr.name,
+		Agreed:    map[string]string{},
+		Disagreed: map[string]map[string]string{},
+		Members:   make([]*groupMemberState, len(gr.members)),
+	}
+	var wg sync.WaitGroup
+	for i, id := range gr.members {
+		wg.Add(1)
+		go func(i, id int) {
+			defer wg.Done()
+			m := &groupMemberState{TV: tvName(id)}
+			defer func() {
+				if p := recover(); p != nil {
+					log.Printf("group %s: %s: panic: %v\n", gr.name, tvName(id), p)
+					m.State, m.Error = nil, &apiError{codeInternal, fmt.Sprint("panic: ", p)}
+				}
+				st.Members[i] = m
+			}()
+			if state, err := tvs[id].State(); err != nil {
+				code, _ := classifyError(err)
+				m.Error = &apiError{code, err.Error()}
+			} else {
+				m.State = state
+			}
+		}(i, id)
+	}
+	wg.Wait()
+
+	shown := map[string]map[string]string{}
+	for i, m := range st.Members {
+		if m.State == nil {
+			continue
+		}
+		for attr, v := range stateValues(gr.members[i], m.State) {
+			if shown[attr.String()] == nil {
+				shown[attr.String()] = map[string]string{}
+			}
+			shown[attr.String()][m.TV] = v
+		}
+	}
+	for attr, byTV := range shown {
+		var values []string
+		for _, v := range byTV {
+			values = append(values, v)
+		}
+		sort.Strings(values)
+		if values[0] == values[len(values)-1] {
+			st.Agreed[attr] = values[0]
+		} else {
+			st.Disagreed[attr] = byTV
+		}
+	}
+	writeJSON(w, http.StatusOK, st)
+}
+
+// serveGroupList lists the groups a request's token covers every member
+// of, with their members.
+func serveGroupList(w http.ResponseWriter, r *http.Request) {
+	g, ok := auth.authenticate(w, r, apiFailure(w))
+	if !ok {
+		return
+	}
+	list := map[string][]string{}
+	for name, gr := range groups {
+		allowed := true
+		for _, id := range gr.members {
+			allowed = allowed && (g == nil || g.allowsTV(id))
+		}
+		if allowed {
+			list[name] = gr.memberNames()
+		}
+	}
+	writeJSON(w, http.StatusOK, list)
+}
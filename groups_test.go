@@ -0,0 +1,278 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/DHowett/avantgarde/tv"
+	"github.com/DHowett/avantgarde/tv/lg"
+)
+
+// useTestGroups configures groups from YAML for a test and returns a
+// function that removes them again.
+func useTestGroups(t *testing.T, config string) func() {
+	var cfgs map[string]GroupConfig
+	if err := yaml.Unmarshal([]byte(config), &cfgs); err != nil {
+		t.Fatalf("Failed to parse groups: %v", err)
+	}
+	g, err := newGroups(cfgs)
+	if err != nil {
+		t.Fatalf("Failed to configure groups: %v", err)
+	}
+	groups = g
+	return func() { groups = map[string]*group{} }
+}
+
+// hdmiTV is a fake that is showing HDMI 1.
+type hdmiTV struct {
+	*fakeTV
+}
+
+func (h hdmiTV) State() (*tv.State, error) {
+	state, err := h.fakeTV.State()
+	state.Input = tv.InputNumber{tv.HDMI, 1}
+	return state, err
+}
+
+func serveGroup(method, path, body, token string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	(&groupServer{newTVServer()}).ServeHTTP(w, r)
+	return w
+}
+
+func decodeGroupReport(t *testing.T, w *httptest.ResponseRecorder) *groupReport {
+	var report groupReport
+	if w.Code != http.StatusOK {
+		t.Fatalf("Got %d instead of 200: %s", w.Code, w.Body)
+	}
+	if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+		t.Fatalf("Failed to decode the report: %v", err)
+	}
+	return &report
+}
+
+func TestNewGroup(t *testing.T) {
+	defer useFakeTVs(&fakeTV{}, &fakeTV{}, &fakeTV{})()
+
+	for _, good := range []string{
+		`[fake0, fake1]`,
+		`{members: [fake0, fake1], broadcast: fake2}`,
+	} {
+		var c GroupConfig
+		if err := yaml.Unmarshal([]byte(good), &c); err != nil {
+			t.Errorf("Failed to parse %s: %v", good, err)
+			continue
+		}
+		if _, err := newGroup("good", c); err != nil {
+			t.Errorf("Failed to configure %s: %v", good, err)
+		}
+	}
+
+	for _, bad := range []string{
+		`[]`,
+		`[fake0, nope]`,
+		`[fake0, fake0]`,
+		`{members: [fake0, fake1], broadcast: nope}`,
+		`{members: [fake0, fake1], broadcast: fake1}`,
+	} {
+		var c GroupConfig
+		if err := yaml.Unmarshal([]byte(bad), &c); err != nil {
+			t.Fatalf("Failed to parse %s: %v", bad, err)
+		}
+		if _, err := newGroup("bad", c); err == nil {
+			t.Errorf("Accepted %s!", bad)
+		}
+	}
+}
+
+func TestGroupCommand(t *testing.T) {
+	fakes := []*fakeTV{{}, {}, {}}
+	defer useFakeTVs(fakes[0], fakes[1], fakes[2])()
+	defer useTestGroups(t, `bar: [fake0, fake2]`)()
+
+	report := decodeGroupReport(t, serveGroup("POST", "/group/bar/volume", "v=10", ""))
+	if report.Status != stepDone || len(report.Results) != 2 || report.Results[0].TV != "fake0" || report.Results[1].Status != http.StatusNoContent {
+		t.Errorf("Got %+v instead of the volume set on both TVs!", report)
+	}
+	for _, i := range []int{0, 2} {
+		if len(fakes[i].ops) != 1 || fmt.Sprint(*fakes[i].ops[0]) != fmt.Sprint(tv.Op{tv.Volume, tv.Set, 10}) {
+			t.Errorf("Got %v instead of a volume change on fake%d!", fakes[i].ops, i)
+		}
+	}
+	if len(fakes[1].ops) != 0 {
+		t.Errorf("Got %v on a TV outside the group!", fakes[1].ops)
+	}
+
+	fakes[2].err = errors.New("fake: broken")
+	report = decodeGroupReport(t, serveGroup("POST", "/group/bar/power", "v=1", ""))
+	if report.Status != stepFailed || report.Results[0].Status != http.StatusNoContent || report.Results[1].Status != http.StatusInternalServerError {
+		t.Errorf("Got %+v instead of fake2 failing!", report)
+	}
+	if string(report.Results[1].Body) != `"fake: broken"` {
+		t.Errorf("Got %s instead of fake2's error!", report.Results[1].Body)
+	}
+
+	// Members are not sent what they do not support.
+	fakes[2].err = nil
+	fakes[0].ops, fakes[2].ops = nil, nil
+	report = decodeGroupReport(t, serveGroup("POST", "/group/bar/screen", "v=1", ""))
+	if report.Status != stepFailed || report.Results[0].Status != http.StatusNotImplemented || len(fakes[0].ops) != 0 || len(fakes[2].ops) != 0 {
+		t.Errorf("Got %+v instead of the screen being unsupported!", report)
+	}
+
+	report = decodeGroupReport(t, serveGroup("GET", "/group/bar/status", "", ""))
+	var state tv.State
+	if err := json.Unmarshal(report.Results[1].Body, &state); err != nil || state.Volume != 15 {
+		t.Errorf("Got %s, %v instead of fake2's state!", report.Results[1].Body, err)
+	}
+
+	for _, test := range []struct {
+		method, path string
+		status       int
+	}{
+		{"POST", "/group/nope/power", http.StatusNotFound},
+		{"POST", "/group/bar/warp", http.StatusNotFound},
+		{"POST", "/group/bar", http.StatusNotFound},
+		{"GET", "/group/bar/power", http.StatusMethodNotAllowed},
+	} {
+		if w := serveGroup(test.method, test.path, "", ""); w.Code != test.status {
+			t.Errorf("Got %d instead of %d for %s %s!", w.Code, test.status, test.method, test.path)
+		}
+	}
+}
+
+func TestGroupBroadcast(t *testing.T) {
+	fakes := []*fakeTV{{}, {}, {}}
+	defer useFakeTVs(fakes[0], fakes[1], fakes[2])()
+	defer useTestGroups(t, `wall: {members: [fake0, fake1], broadcast: fake2}`)()
+
+	report := decodeGroupReport(t, serveGroup("POST", "/group/wall/power", "v=1", ""))
+	if report.Status != stepDone || len(report.Results) != 1 || report.Results[0].TV != "fake2" {
+		t.Errorf("Got %+v instead of one broadcast!", report)
+	}
+	if len(fakes[0].ops) != 0 || len(fakes[1].ops) != 0 || len(fakes[2].ops) != 1 {
+		t.Errorf("Got %v, %v and %v instead of only the broadcast TV sent the command!", fakes[0].ops, fakes[1].ops, fakes[2].ops)
+	}
+
+	// State is still read from each member.
+	report = decodeGroupReport(t, serveGroup("GET", "/group/wall/status", "", ""))
+	if len(report.Results) != 2 || report.Results[1].TV != "fake1" {
+		t.Errorf("Got %+v instead of the members' states!", report)
+	}
+}
+
+// TestBroadcastConfig checks that the LG video wall in the README
+// configures what it says it does.
+func TestBroadcastConfig(t *testing.T) {
+	var cfg Config
+	err := yaml.Unmarshal([]byte(`
+tvs:
+  - {name: wall-all, model: lg, port: /dev/ttyUSB0, setid: 0}
+  - {name: wall-1, model: lg, port: /dev/ttyUSB0, setid: 1}
+groups:
+  wall:
+    members: [wall-1]
+    broadcast: wall-all
+`), &cfg)
+	if err != nil {
+		t.Fatalf("Failed to parse the configuration: %v", err)
+	}
+	for i, tvc := range cfg.TVs {
+		lgc, ok := tvc.ModelSpecific.(*lg.Config)
+		if tvc.V.Device != "/dev/ttyUSB0" || !ok || int(lgc.SetID) != i {
+			t.Errorf("Got %+v and %+v instead of set ID %d on /dev/ttyUSB0!", tvc.V, tvc.ModelSpecific, i)
+		}
+	}
+	if cfg.Groups["wall"].Broadcast != "wall-all" {
+		t.Errorf("Got %+v instead of broadcasting through wall-all!", cfg.Groups["wall"])
+	}
+}
+
+func TestGroupState(t *testing.T) {
+	defer useFakeTVs(&fakeTV{}, hdmiTV{&fakeTV{}}, &fakeTV{err: errors.New("fake: broken")})()
+	defer useTestGroups(t, `bar: [fake0, fake1, fake2]`)()
+
+	w := serveGroup("GET", "/group/bar/state", "", "")
+	var st groupState
+	if err := json.NewDecoder(w.Body).Decode(&st); err != nil {
+		t.Fatalf("Failed to decode the state: %v", err)
+	}
+	if st.Agreed["volume"] != "15" || st.Agreed["power"] != "ON" || len(st.Agreed) != 3 {
+		t.Errorf("Got %v instead of agreeing on power, volume and mute!", st.Agreed)
+	}
+	input := st.Disagreed["input"]
+	if len(st.Disagreed) != 1 || len(input) != 2 || input["fake1"] != "hdmi 1" {
+		t.Errorf("Got %v instead of fake1 alone showing HDMI 1!", st.Disagreed)
+	}
+	if len(st.Members) != 3 || st.Members[1].State == nil || st.Members[2].Error == nil || st.Members[2].Error.Message != "fake: broken" {
+		t.Errorf("Got %+v instead of every member's state or error!", st.Members)
+	}
+}
+
+func TestGroupPanic(t *testing.T) {
+	defer useFakeTVs(&fakeTV{}, &panicTV{})()
+	defer useTestGroups(t, `bar: [fake0, fake1]`)()
+
+	// A member whose driver panics fails on its own.
+	report := decodeGroupReport(t, serveGroup("POST", "/group/bar/power", "v=1", ""))
+	if report.Status != stepFailed || report.Results[0].Status != http.StatusNoContent || report.Results[1].TV != "fake1" || report.Results[1].Status != http.StatusInternalServerError {
+		t.Errorf("Got %+v instead of fake1 failing!", report)
+	}
+
+	w := serveGroup("GET", "/group/bar/state", "", "")
+	var st groupState
+	if err := json.NewDecoder(w.Body).Decode(&st); err != nil {
+		t.Fatalf("Failed to decode the state: %v", err)
+	}
+	if len(st.Members) != 2 || st.Members[0].State == nil || st.Members[1].Error == nil || st.Members[1].Error.Code != codeInternal {
+		t.Errorf("Got %+v instead of fake1 failing!", st.Members)
+	}
+}
+
+func TestGroupAuth(t *testing.T) {
+	defer useFakeTVs(&fakeTV{}, &fakeTV{})()
+	defer useTestTokens(t)()
+	defer useTestGroups(t, `
+bar: [fake1]
+all: [fake0, fake1]
+`)()
+
+	for _, test := range []struct {
+		method, path, token string
+		status              int
+	}{
+		{"POST", "/group/bar/volume", "", http.StatusUnauthorized},
+		{"POST", "/group/bar/volume", "bar-secret", http.StatusOK},
+		{"POST", "/group/bar/power", "bar-secret", http.StatusForbidden},
+		{"POST", "/group/all/volume", "bar-secret", http.StatusForbidden},
+		{"GET", "/group/all/state", "bar-secret", http.StatusForbidden},
+		{"POST", "/group/all/power", "admin-secret", http.StatusOK},
+	} {
+		if w := serveGroup(test.method, test.path, "v=1", test.token); w.Code != test.status {
+			t.Errorf("Got %d instead of %d for %s %s with %q!", w.Code, test.status, test.method, test.path, test.token)
+		}
+	}
+
+	r := httptest.NewRequest("GET", "/groups", nil)
+	r.Header.Set("X-API-Key", "bar-secret")
+	w := httptest.NewRecorder()
+	serveGroupList(w, r)
+	var list map[string][]string
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil || len(list) != 1 || len(list["bar"]) != 1 {
+		t.Errorf("Got %v, %v instead of the groups bar-secret may use!", list, err)
+	}
+}
@@ -0,0 +1,436 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/DHowett/avantgarde/rpc"
+	"github.com/DHowett/avantgarde/tv"
+)
+
+// grpcCodes maps the JSON API's error codes onto gRPC's.
+var grpcCodes = map[string]codes.Code{
+	codeUnsupported:  codes.Unimplemented,
+	codeInvalidValue: codes.InvalidArgument,
+	codeTimeout:      codes.DeadlineExceeded,
+	codeDisconnected: codes.Unavailable,
+	codeInternal:     codes.Internal,
+}
+
+func grpcError(err error) error {
+	code, _ := classifyError(err)
+	return status.Error(grpcCodes[code], err.Error())
+}
+
+// grpcServer serves rpc.TVService, the gRPC counterpart of the JSON API.
+type grpcServer struct {
+	rpc.UnimplementedTVServiceServer
+}
+
+// newGRPCServer serves the gRPC API, over TLS if tlsConfig is not nil.
+func newGRPCServer(tlsConfig *tls.Config) *grpc.Server {
+	var opts []grpc.ServerOption
+	if tlsConfig != nil {
+		// gRPC clients insist on negotiating HTTP/2. credentials.NewTLS
+		// offers it on the config it is given, but not on the ones
+		// GetConfigForClient hands out per connection, as a certReloader's do.
+		cfg := tlsConfig.Clone()
+		if get := cfg.GetConfigForClient; get != nil {
+			cfg.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+				c, err := get(hello)
+				if c != nil {
+					c.NextProtos = []string{"h2"}
+				}
+				return c, err
+			}
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(cfg)))
+	}
+	opts = append(opts, grpc.UnaryInterceptor(recoverUnary), grpc.StreamInterceptor(recoverStream))
+	s := grpc.NewServer(opts...)
+	rpc.RegisterTVServiceServer(s, &grpcServer{})
+	return s
+}
+
+// recoverUnary and recoverStream turn a handler's panic, such as one from a
+// driver, into an Internal error. grpc-go does not catch them, and one
+// would otherwise take the whole server down.
+func recoverUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("grpc: %s: panic: %v\n", info.FullMethod, r)
+			resp, err = nil, status.Error(codes.Internal, fmt.Sprint("panic: ", r))
+		}
+	}()
+	return handler(ctx, req)
+}
+
+func recoverStream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("grpc: %s: panic: %v\n", info.FullMethod, r)
+			err = status.Error(codes.Internal, fmt.Sprint("panic: ", r))
+		}
+	}()
+	return handler(srv, ss)
+}
+
+func callerAddress(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok {
+		return p.Addr.String()
+	}
+	return "unknown"
+}
+
+// callToken returns the token presented as "authorization: Bearer" or as
+// "x-api-key" metadata.
+func callToken(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if v := md.Get("authorization"); len(v) > 0 && strings.HasPrefix(v[0], "Bearer ") {
+		return strings.TrimPrefix(v[0], "Bearer ")
+	}
+	if v := md.Get("x-api-key"); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// authenticateCall checks that a call carries a valid token and returns its
+// grant, which is nil if tokens are not configured.
+func authenticateCall(ctx context.Context) (*grant, error) {
+	if auth == nil {
+		return nil, nil
+	}
+	g := auth.grantForToken(callToken(ctx))
+	if g == nil {
+		method, _ := grpc.Method(ctx)
+		logUnauthorizedCall(method, callerAddress(ctx), nil, "not authenticated")
+		return nil, status.Error(codes.Unauthenticated, "a valid token is required")
+	}
+	return g, nil
+}
+
+// authorizeCall resolves a TV by name or number and checks that the call
+// may act on an attribute of it; attr is 0 for calls that are not tied to
+// one.
+func authorizeCall(ctx context.Context, name string, attr tv.Attribute, write bool) (int, *grant, error) {
+	g, err := authenticateCall(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	id, ok := findTV(name)
+	if !ok {
+		return 0, nil, status.Errorf(codes.NotFound, "no such tv %q", name)
+	}
+	if g != nil && !g.allows(id, attr, write) {
+		what := fmt.Sprintf("tv %d", id)
+		if attr != 0 {
+			what = fmt.Sprintf("%s of tv %d", attr, id)
+		}
+		method, _ := grpc.Method(ctx)
+		logUnauthorizedCall(method, callerAddress(ctx), g, "not allowed "+what)
+		return 0, nil, status.Error(codes.PermissionDenied, "this token may not use "+what)
+	}
+	return id, g, nil
+}
+
+func rpcInput(i tv.InputNumber) *rpc.InputNumber {
+	return &rpc.InputNumber{Connection: rpc.Connection(i.Connection + 1), Number: int32(i.Number)}
+}
+
+func rpcChannel(ch tv.Channel) *rpc.Channel {
+	switch ch := ch.(type) {
+	case tv.AnalogChannel:
+		return &rpc.Channel{Channel: &rpc.Channel_Analog{uint32(ch)}}
+	case tv.DigitalChannel:
+		return &rpc.Channel{Channel: &rpc.Channel_Digital{&rpc.DigitalChannel{Channel: uint32(ch.Ch), Subchannel: uint32(ch.Sub)}}}
+	}
+	return nil
+}
+
+func rpcState(s *tv.State) *rpc.State {
+	return &rpc.State{
+		Power:   s.Power,
+		Volume:  int32(s.Volume),
+		Mute:    s.Mute,
+		Screen:  s.Screen,
+		Channel: rpcChannel(s.Channel),
+		Input:   rpcInput(s.Input),
+	}
+}
+
+var rpcConnectionStates = map[string]rpc.ConnectionState{
+	"unknown":      rpc.ConnectionState_CONNECTION_STATE_UNKNOWN,
+	"connected":    rpc.ConnectionState_CONNECTION_STATE_CONNECTED,
+	"disconnected": rpc.ConnectionState_CONNECTION_STATE_DISCONNECTED,
+}
+
+func rpcCapabilities(id int) []*rpc.Capability {
+	c, ok := tvs[id].(tv.Capable)
+	if !ok {
+		return nil
+	}
+	var list []*rpc.Capability
+	for _, capability := range c.Capabilities() {
+		if capability.Attribute == tv.Raw && !rawAllowed(id) {
+			continue
+		}
+		rc := &rpc.Capability{
+			Attribute: rpc.Attribute(capability.Attribute),
+			Min:       int32(capability.Min),
+			Max:       int32(capability.Max),
+		}
+		for _, o := range capability.Operators {
+			rc.Operators = append(rc.Operators, rpc.Operator(o))
+		}
+		for _, i := range capability.Inputs {
+			rc.Inputs = append(rc.Inputs, rpcInput(i))
+		}
+		list = append(list, rc)
+	}
+	return list
+}
+
+func rpcEvent(ev *stateEvent) *rpc.StateEvent {
+	re := &rpc.StateEvent{
+		Id:         int32(ev.ID),
+		Name:       ev.Name,
+		Connection: rpcConnectionStates[ev.Connection],
+		Error:      ev.Error,
+	}
+	if ev.State != nil {
+		re.State = rpcState(ev.State)
+	}
+	return re
+}
+
+func tvInput(i *rpc.InputNumber) (tv.InputNumber, error) {
+	if i.GetConnection() <= rpc.Connection_CONNECTION_UNSPECIFIED || i.GetConnection() > rpc.Connection_CONNECTION_SPECIAL {
+		return tv.InputNumber{}, fmt.Errorf("unknown connection %v", i.GetConnection())
+	}
+	return tv.InputNumber{tv.Connection(i.Connection - 1), int(i.Number)}, nil
+}
+
+func tvChannel(ch *rpc.Channel) (tv.Channel, error) {
+	switch ch := ch.GetChannel().(type) {
+	case *rpc.Channel_Analog:
+		return tv.AnalogChannel(ch.Analog), nil
+	case *rpc.Channel_Digital:
+		return tv.DigitalChannel{uint(ch.Digital.GetChannel()), uint(ch.Digital.GetSubchannel())}, nil
+	}
+	return nil, errors.New("missing channel")
+}
+
+// toOp converts a DoRequest into the operation TVs expect, checking that
+// its value has the type the attribute takes.
+func toOp(req *rpc.DoRequest) (*tv.Op, error) {
+	attr := tv.Attribute(req.Attribute)
+	if _, err := tv.ParseAttribute(attr.String()); err != nil {
+		return nil, fmt.Errorf("%v: %w", err, tv.ErrInvalidValue)
+	}
+	operator := tv.Set
+	if req.Operator != rpc.Operator_OPERATOR_UNSPECIFIED {
+		operator = tv.Operator(req.Operator)
+		if _, err := tv.ParseOperator(operator.String()); err != nil {
+			return nil, fmt.Errorf("%v: %w", err, tv.ErrInvalidValue)
+		}
+	}
+	if err := checkOperator(attr, operator); err != nil {
+		return nil, err
+	}
+
+	wrongType := fmt.Errorf("%s: wrong type of value %T: %w", attr, req.Value, tv.ErrInvalidValue)
+	switch operator {
+	case tv.Toggle, tv.Query:
+		return &tv.Op{attr, operator, nil}, nil
+	case tv.Increment, tv.Decrement:
+		switch v := req.Value.(type) {
+		case nil:
+			return &tv.Op{attr, operator, 1}, nil
+		case *rpc.DoRequest_Level:
+			return &tv.Op{attr, operator, int(v.Level)}, nil
+		}
+		return nil, wrongType
+	}
+
+	if req.Value == nil {
+		return nil, fmt.Errorf("%s: missing value: %w", attr, tv.ErrInvalidValue)
+	}
+	var value interface{}
+	switch v := req.Value.(type) {
+	case *rpc.DoRequest_On:
+		switch attr {
+		case tv.Power, tv.Mute, tv.Screen, tv.OSD, tv.Lock, tv.PIP:
+			value = v.On
+		}
+	case *rpc.DoRequest_Level:
+		switch attr {
+		case tv.Volume, tv.Contrast, tv.Brightness, tv.Color, tv.Tint, tv.Sharpness,
+			tv.AudioBalance, tv.ColorTemperature, tv.Backlight:
+			value = int(v.Level)
+		}
+	case *rpc.DoRequest_Input:
+		if attr == tv.Input {
+			input, err := tvInput(v.Input)
+			if err != nil {
+				return nil, invalidValue(attr, err)
+			}
+			value = input
+		}
+	case *rpc.DoRequest_Tune:
+		if attr == tv.Tuning {
+			ch, err := tvChannel(v.Tune.GetChannel())
+			if err != nil {
+				return nil, invalidValue(attr, err)
+			}
+			antenna := tv.Antenna(v.Tune.Antenna)
+			if antenna == 0 {
+				antenna = 0x01
+			}
+			value = tv.Tune{antenna, ch}
+		}
+	case *rpc.DoRequest_Raw:
+		if attr == tv.Raw {
+			value = v.Raw
+		}
+	}
+	if value == nil {
+		return nil, wrongType
+	}
+	return &tv.Op{attr, operator, value}, nil
+}
+
+func (s *grpcServer) ListTVs(ctx context.Context, req *rpc.ListTVsRequest) (*rpc.ListTVsResponse, error) {
+	g, err := authenticateCall(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp := &rpc.ListTVsResponse{}
+	for i := range tvs {
+		if g != nil && !g.allowsTV(i) {
+			continue
+		}
+		resp.Tvs = append(resp.Tvs, &rpc.TV{
+			Id:           int32(i),
+			Name:         tvConfigs[i].V.Name,
+			Model:        tvConfigs[i].V.Model,
+			Connection:   rpcConnectionStates[connectionState(i)],
+			Capabilities: rpcCapabilities(i),
+		})
+	}
+	return resp, nil
+}
+
+func (s *grpcServer) Capabilities(ctx context.Context, req *rpc.CapabilitiesRequest) (*rpc.CapabilitiesResponse, error) {
+	id, _, err := authorizeCall(ctx, req.Tv, 0, false)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := tvs[id].(tv.Capable); !ok {
+		return nil, grpcError(fmt.Errorf("%s cannot report its capabilities: %w", req.Tv, tv.ErrUnsupported))
+	}
+	return &rpc.CapabilitiesResponse{Capabilities: rpcCapabilities(id)}, nil
+}
+
+func (s *grpcServer) State(ctx context.Context, req *rpc.StateRequest) (*rpc.StateResponse, error) {
+	id, _, err := authorizeCall(ctx, req.Tv, 0, false)
+	if err != nil {
+		return nil, err
+	}
+	state, err := tvs[id].State()
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &rpc.StateResponse{State: rpcState(state)}, nil
+}
+
+func (s *grpcServer) Do(ctx context.Context, req *rpc.DoRequest) (*rpc.DoResponse, error) {
+	// Tell callers without a token only that, not what is wrong with
+	// their request.
+	if _, err := authenticateCall(ctx); err != nil {
+		return nil, err
+	}
+	op, err := toOp(req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	id, g, err := authorizeCall(ctx, req.Tv, op.Attribute, true)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &rpc.DoResponse{}
+	if op.Attribute == tv.Raw {
+		resp.RawReply, err = doRaw(id, op.Value.([]byte), callerAddress(ctx), g)
+		if errors.Is(err, errRawDisabled) {
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
+	} else if err = checkSupported(tvs[id], op); err == nil {
+		err = doOp(tvs[id], op)
+	}
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	hub.changed(id)
+	return resp, nil
+}
+
+func (s *grpcServer) Watch(req *rpc.WatchRequest, stream grpc.ServerStreamingServer[rpc.StateEvent]) error {
+	ctx := stream.Context()
+	g, err := authenticateCall(ctx)
+	if err != nil {
+		return err
+	}
+	h := hub
+	if h == nil {
+		return status.Error(codes.Unavailable, "state is not being followed")
+	}
+
+	var only map[int]bool
+	for _, name := range req.Tvs {
+		id, _, err := authorizeCall(ctx, name, 0, false)
+		if err != nil {
+			return err
+		}
+		if only == nil {
+			only = make(map[int]bool)
+		}
+		only[id] = true
+	}
+
+	for {
+		// The hub drops subscribers that fall behind; subscribing again
+		// starts over with the latest state.
+		events := h.subscribe()
+	receive:
+		for {
+			select {
+			case <-ctx.Done():
+				h.unsubscribe(events)
+				return nil
+			case ev, ok := <-events:
+				if !ok {
+					break receive
+				}
+				if only != nil && !only[ev.ID] || g != nil && !g.allowsTV(ev.ID) {
+					continue
+				}
+				if err := stream.Send(rpcEvent(ev)); err != nil {
+					h.unsubscribe(events)
+					return err
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/DHowett/avantgarde/rpc"
+	"github.com/DHowett/avantgarde/tv"
+)
+
+// useGRPC serves the gRPC API in memory and returns a client for it.
+func useGRPC(t *testing.T) rpc.TVServiceClient {
+	lis := bufconn.Listen(1 << 16)
+	s := newGRPCServer(nil)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return rpc.NewTVServiceClient(conn)
+}
+
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func TestToOp(t *testing.T) {
+	digital := &rpc.Tune{Channel: &rpc.Channel{Channel: &rpc.Channel_Digital{&rpc.DigitalChannel{Channel: 7, Subchannel: 1}}}}
+	tests := []struct {
+		req *rpc.DoRequest
+		op  tv.Op
+	}{
+		{&rpc.DoRequest{Attribute: rpc.Attribute_ATTRIBUTE_POWER, Value: &rpc.DoRequest_On{true}}, tv.Op{tv.Power, tv.Set, true}},
+		{&rpc.DoRequest{Attribute: rpc.Attribute_ATTRIBUTE_VOLUME, Operator: rpc.Operator_OPERATOR_INCREMENT}, tv.Op{tv.Volume, tv.Increment, 1}},
+		{&rpc.DoRequest{Attribute: rpc.Attribute_ATTRIBUTE_VOLUME, Operator: rpc.Operator_OPERATOR_DECREMENT, Value: &rpc.DoRequest_Level{3}}, tv.Op{tv.Volume, tv.Decrement, 3}},
+		{&rpc.DoRequest{Attribute: rpc.Attribute_ATTRIBUTE_INPUT, Value: &rpc.DoRequest_Input{&rpc.InputNumber{Connection: rpc.Connection_CONNECTION_HDMI, Number: 2}}}, tv.Op{tv.Input, tv.Set, tv.InputNumber{tv.HDMI, 2}}},
+		{&rpc.DoRequest{Attribute: rpc.Attribute_ATTRIBUTE_TUNING, Value: &rpc.DoRequest_Tune{digital}}, tv.Op{tv.Tuning, tv.Set, tv.Tune{0x01, tv.DigitalChannel{7, 1}}}},
+		{&rpc.DoRequest{Attribute: rpc.Attribute_ATTRIBUTE_MUTE, Operator: rpc.Operator_OPERATOR_TOGGLE}, tv.Op{tv.Mute, tv.Toggle, nil}},
+	}
+	for _, test := range tests {
+		op, err := toOp(test.req)
+		if err != nil {
+			t.Errorf("Failed to convert %v: %v", test.req, err)
+			continue
+		}
+		if fmt.Sprint(*op) != fmt.Sprint(test.op) {
+			t.Errorf("Got %v instead of %v for %v!", *op, test.op, test.req)
+		}
+	}
+
+	for _, bad := range []*rpc.DoRequest{
+		{Attribute: rpc.Attribute_ATTRIBUTE_POWER, Value: &rpc.DoRequest_Level{1}},
+		{Attribute: rpc.Attribute_ATTRIBUTE_VOLUME},
+		{Attribute: rpc.Attribute_ATTRIBUTE_INPUT, Value: &rpc.DoRequest_Input{&rpc.InputNumber{Number: 2}}},
+		{Attribute: rpc.Attribute_ATTRIBUTE_TUNING, Value: &rpc.DoRequest_Tune{&rpc.Tune{}}},
+		{Attribute: rpc.Attribute_ATTRIBUTE_UNSPECIFIED, Value: &rpc.DoRequest_On{true}},
+		{Attribute: 99, Value: &rpc.DoRequest_On{true}},
+		{Attribute: rpc.Attribute_ATTRIBUTE_RAW, Operator: rpc.Operator_OPERATOR_TOGGLE},
+		{Attribute: rpc.Attribute_ATTRIBUTE_RAW, Operator: rpc.Operator_OPERATOR_QUERY},
+		{Attribute: rpc.Attribute_ATTRIBUTE_VOLUME, Operator: rpc.Operator_OPERATOR_TOGGLE},
+		{Attribute: rpc.Attribute_ATTRIBUTE_INPUT, Operator: rpc.Operator_OPERATOR_INCREMENT},
+	} {
+		if op, err := toOp(bad); !errors.Is(err, tv.ErrInvalidValue) {
+			t.Errorf("Got %v, %v instead of an invalid value for %v!", op, err, bad)
+		}
+	}
+}
+
+func TestGRPC(t *testing.T) {
+	fake := &fakeTV{}
+	defer useFakeTVs(fake, struct{ tv.TV }{&fakeTV{}})()
+	client := useGRPC(t)
+	ctx := context.Background()
+
+	list, err := client.ListTVs(ctx, &rpc.ListTVsRequest{})
+	if err != nil {
+		t.Fatalf("Failed to list TVs: %v", err)
+	}
+	if len(list.Tvs) != 2 || list.Tvs[0].Name != "fake0" || len(list.Tvs[0].Capabilities) != 4 || len(list.Tvs[1].Capabilities) != 0 {
+		t.Errorf("Got %v instead of both TVs!", list.Tvs)
+	}
+
+	caps, err := client.Capabilities(ctx, &rpc.CapabilitiesRequest{Tv: "fake0"})
+	if err != nil || caps.Capabilities[1].Attribute != rpc.Attribute_ATTRIBUTE_VOLUME || caps.Capabilities[1].Max != 100 {
+		t.Errorf("Got %v, %v instead of the fake's capabilities!", caps, err)
+	}
+	if _, err := client.Capabilities(ctx, &rpc.CapabilitiesRequest{Tv: "fake1"}); status.Code(err) != codes.Unimplemented {
+		t.Errorf("Got %v instead of unimplemented for a TV that cannot tell!", err)
+	}
+
+	if _, err := client.Do(ctx, &rpc.DoRequest{Tv: "fake0", Attribute: rpc.Attribute_ATTRIBUTE_VOLUME, Value: &rpc.DoRequest_Level{20}}); err != nil {
+		t.Errorf("Failed to set the volume: %v", err)
+	}
+	if len(fake.ops) != 1 || fmt.Sprint(*fake.ops[0]) != fmt.Sprint(tv.Op{tv.Volume, tv.Set, 20}) {
+		t.Errorf("Got %v instead of a volume change!", fake.ops)
+	}
+
+	state, err := client.State(ctx, &rpc.StateRequest{Tv: "0"})
+	if err != nil || !state.State.Power || state.State.Volume != 15 || state.State.Input.Connection != rpc.Connection_CONNECTION_COAXIAL {
+		t.Errorf("Got %v, %v instead of the fake's state!", state, err)
+	}
+
+	for _, test := range []struct {
+		req  *rpc.DoRequest
+		err  error
+		code codes.Code
+	}{
+		{&rpc.DoRequest{Tv: "nope", Attribute: rpc.Attribute_ATTRIBUTE_POWER, Value: &rpc.DoRequest_On{true}}, nil, codes.NotFound},
+		{&rpc.DoRequest{Tv: "fake0", Attribute: rpc.Attribute_ATTRIBUTE_POWER}, nil, codes.InvalidArgument},
+		{&rpc.DoRequest{Tv: "fake0", Attribute: rpc.Attribute_ATTRIBUTE_RAW, Value: &rpc.DoRequest_Raw{[]byte("ka 01 01")}}, nil, codes.PermissionDenied},
+		{&rpc.DoRequest{Tv: "fake0", Attribute: rpc.Attribute_ATTRIBUTE_RAW, Operator: rpc.Operator_OPERATOR_TOGGLE}, nil, codes.InvalidArgument},
+		{&rpc.DoRequest{Tv: "fake0", Attribute: rpc.Attribute_ATTRIBUTE_POWER, Operator: rpc.Operator_OPERATOR_TOGGLE}, nil, codes.Unimplemented},
+		{&rpc.DoRequest{Tv: "fake0", Attribute: rpc.Attribute_ATTRIBUTE_POWER, Value: &rpc.DoRequest_On{true}}, fmt.Errorf("fake: %w", tv.ErrUnsupported), codes.Unimplemented},
+		{&rpc.DoRequest{Tv: "fake0", Attribute: rpc.Attribute_ATTRIBUTE_POWER, Value: &rpc.DoRequest_On{true}}, fmt.Errorf("fake: %w", tv.ErrTimeout), codes.DeadlineExceeded},
+	} {
+		fake.err = test.err
+		if _, err := client.Do(ctx, test.req); status.Code(err) != test.code {
+			t.Errorf("Got %v instead of %v for %v!", err, test.code, test.req)
+		}
+	}
+}
+
+func TestGRPCRaw(t *testing.T) {
+	fake := &fakeTV{reply: []byte("OK")}
+	defer useFakeTVs(fake)()
+	tvConfigs[0].V.AllowRaw = true
+	client := useGRPC(t)
+
+	resp, err := client.Do(context.Background(), &rpc.DoRequest{Tv: "fake0", Attribute: rpc.Attribute_ATTRIBUTE_RAW, Value: &rpc.DoRequest_Raw{[]byte("ka 01 01")}})
+	if err != nil || string(resp.RawReply) != "OK" {
+		t.Errorf("Got %v, %v instead of the TV's reply!", resp, err)
+	}
+}
+
+func TestGRPCAuth(t *testing.T) {
+	defer useFakeTVs(&fakeTV{}, &fakeTV{})()
+	defer useTestTokens(t)()
+	client := useGRPC(t)
+
+	volume := &rpc.DoRequest{Tv: "fake1", Attribute: rpc.Attribute_ATTRIBUTE_VOLUME, Value: &rpc.DoRequest_Level{10}}
+	power := &rpc.DoRequest{Tv: "fake1", Attribute: rpc.Attribute_ATTRIBUTE_POWER, Value: &rpc.DoRequest_On{true}}
+	toggleRaw := &rpc.DoRequest{Tv: "fake1", Attribute: rpc.Attribute_ATTRIBUTE_RAW, Operator: rpc.Operator_OPERATOR_TOGGLE}
+	for _, test := range []struct {
+		ctx  context.Context
+		req  *rpc.DoRequest
+		code codes.Code
+	}{
+		{context.Background(), volume, codes.Unauthenticated},
+		{withToken("wrong"), volume, codes.Unauthenticated},
+		{context.Background(), toggleRaw, codes.Unauthenticated},
+		{withToken("bar-secret"), toggleRaw, codes.InvalidArgument},
+		{withToken("bar-secret"), volume, codes.OK},
+		{withToken("bar-secret"), power, codes.PermissionDenied},
+		{metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "admin-secret"), power, codes.OK},
+	} {
+		if _, err := client.Do(test.ctx, test.req); status.Code(err) != test.code {
+			t.Errorf("Got %v instead of %v for %v!", err, test.code, test.req)
+		}
+	}
+
+	list, err := client.ListTVs(withToken("bar-secret"), &rpc.ListTVsRequest{})
+	if err != nil || len(list.Tvs) != 1 || list.Tvs[0].Name != "fake1" {
+		t.Errorf("Got %v, %v instead of only the bar's TV!", list, err)
+	}
+	if _, err := client.State(withToken("bar-secret"), &rpc.StateRequest{Tv: "fake0"}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Got %v instead of permission denied!", err)
+	}
+}
+
+func TestGRPCWatch(t *testing.T) {
+	fakes := []*fakeTV{{}, {}}
+	defer useFakeTVs(fakes[0], fakes[1])()
+	defer useTestHub()()
+	client := useGRPC(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := client.Watch(ctx, &rpc.WatchRequest{Tvs: []string{"fake1"}})
+	if err != nil {
+		t.Fatalf("Failed to watch: %v", err)
+	}
+
+	// The stream starts with the TV's last known state.
+	ev, err := stream.Recv()
+	if err != nil || ev.Id != 1 || ev.Name != "fake1" || ev.State.GetVolume() != 15 {
+		t.Fatalf("Got %v, %v instead of the state of fake1!", ev, err)
+	}
+
+	fakes[0].err = errors.New("fake: broken")
+	hub.poll(0)
+	fakes[1].err = errors.New("fake: broken")
+	hub.poll(1)
+	ev, err = stream.Recv()
+	if err != nil || ev.Id != 1 || ev.State != nil || ev.Error != "fake: broken" {
+		t.Errorf("Got %v, %v instead of the error from fake1!", ev, err)
+	}
+
+	// Streams report errors when they are read.
+	bad, err := client.Watch(ctx, &rpc.WatchRequest{Tvs: []string{"nope"}})
+	if err == nil {
+		_, err = bad.Recv()
+	}
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Got %v instead of not found!", err)
+	}
+}
+
+func TestGRPCNegotiatesHTTP2(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	ca := newTestCert(t, "ca", nil)
+	newTestCert(t, "server", ca).write(t, certFile, keyFile, time.Now())
+	certs, err := newCertReloader(certFile, keyFile, "")
+	if err != nil {
+		t.Fatalf("Failed to load certificates: %v", err)
+	}
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newGRPCServer(certs.TLSConfig())
+	go s.Serve(lis)
+	defer s.Stop()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	conn, err := tls.Dial("tcp", lis.Addr().String(), &tls.Config{RootCAs: roots, NextProtos: []string{"h2"}})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	if proto := conn.ConnectionState().NegotiatedProtocol; proto != "h2" {
+		t.Errorf("Got %q instead of h2!", proto)
+	}
+}
+
+func TestGRPCPanic(t *testing.T) {
+	defer useFakeTVs(&panicTV{})()
+	client := useGRPC(t)
+
+	power := &rpc.DoRequest{Tv: "fake0", Attribute: rpc.Attribute_ATTRIBUTE_POWER, Value: &rpc.DoRequest_On{true}}
+	if _, err := client.Do(context.Background(), power); status.Code(err) != codes.Internal {
+		t.Errorf("Got %v instead of an internal error!", err)
+	}
+	// The server is still there to answer.
+	if _, err := client.State(context.Background(), &rpc.StateRequest{Tv: "fake0"}); status.Code(err) != codes.Internal {
+		t.Errorf("Got %v instead of an internal error!", err)
+	}
+}
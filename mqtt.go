@@ -0,0 +1,426 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/DHowett/avantgarde/tv"
+)
+
+// MQTTConfig connects avantgarde to an MQTT broker, where it publishes the
+// state of every TV and takes commands. Tokens do not apply there: anyone
+// the broker lets publish under the prefix can drive every TV.
+type MQTTConfig struct {
+	// Broker is a URL like tcp://10.0.0.2:1883 or ssl://broker:8883.
+	Broker   string
+	ClientID string `yaml:"client_id"`
+	Username string
+	Password string
+	// Prefix starts every topic; it is "avantgarde" if empty.
+	Prefix string
+	// DiscoveryPrefix is where Home Assistant looks for discovery
+	// configurations; it is "homeassistant" if empty, and "-" turns
+	// discovery off.
+	DiscoveryPrefix string `yaml:"discovery_prefix"`
+}
+
+const (
+	payloadOn      = "ON"
+	payloadOff     = "OFF"
+	payloadOnline  = "online"
+	payloadOffline = "offline"
+)
+
+// mqttBridge publishes every TV's state to <prefix>/<tv>/<attribute> and
+// performs commands sent to <prefix>/<tv>/<attribute>/set. The broker marks
+// the bridge offline at <prefix>/status if it goes away; each TV's own
+// availability follows its driver's connection.
+type mqttBridge struct {
+	config MQTTConfig
+	client mqtt.Client
+
+	mu sync.Mutex
+	// last holds the latest state of each TV, to publish again after
+	// reconnecting to the broker.
+	last map[int]*stateEvent
+
+	quit chan struct{}
+	done chan struct{}
+	// handlers tracks the client's callbacks, which it does not wait for
+	// when disconnecting.
+	handlers sync.WaitGroup
+}
+
+func newMQTTBridge(c MQTTConfig) *mqttBridge {
+	if c.Prefix == "" {
+		c.Prefix = "avantgarde"
+	}
+	if c.DiscoveryPrefix == "" {
+		c.DiscoveryPrefix = "homeassistant"
+	}
+	if c.ClientID == "" {
+		c.ClientID = c.Prefix
+	}
+	b := &mqttBridge{
+		config: c,
+		last:   make(map[int]*stateEvent),
+		quit:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(c.Broker).
+		SetClientID(c.ClientID).
+		SetUsername(c.Username).
+		SetPassword(c.Password).
+		SetWill(b.topic("status"), payloadOffline, 1, true).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(10 * time.Second).
+		SetOnConnectHandler(b.connected)
+	b.client = mqtt.NewClient(opts)
+	return b
+}
+
+func (b *mqttBridge) topic(parts ...string) string {
+	return b.config.Prefix + "/" + strings.Join(parts, "/")
+}
+
+// publish sends a retained message, which new subscribers receive at once.
+func (b *mqttBridge) publish(topic string, payload interface{}) mqtt.Token {
+	return b.client.Publish(topic, 1, true, payload)
+}
+
+// start connects to the broker, retrying in the background until it
+// succeeds, and follows the hub's state changes.
+func (b *mqttBridge) start(h *stateHub) {
+	b.client.Connect()
+	go b.follow(h)
+}
+
+// stop stops following the hub and leaves the broker, marking the bridge
+// offline on the way out.
+func (b *mqttBridge) stop() {
+	close(b.quit)
+	<-b.done
+	b.publish(b.topic("status"), payloadOffline).WaitTimeout(time.Second)
+	b.client.Disconnect(250)
+	b.handlers.Wait()
+}
+
+func (b *mqttBridge) follow(h *stateHub) {
+	defer close(b.done)
+	for {
+		// The hub drops subscribers that fall behind; subscribing again
+		// starts over with the latest state.
+		events := h.subscribe()
+	receive:
+		for {
+			select {
+			case <-b.quit:
+				h.unsubscribe(events)
+				return
+			case ev, ok := <-events:
+				if !ok {
+					break receive
+				}
+				b.mu.Lock()
+				b.last[ev.ID] = ev
+				b.mu.Unlock()
+				b.publishState(ev)
+			}
+		}
+	}
+}
+
+// connected runs whenever the connection to the broker is established, as
+// retained messages and subscriptions may have been lost with it.
+func (b *mqttBridge) connected(c mqtt.Client) {
+	b.handlers.Add(1)
+	defer b.handlers.Done()
+
+	b.publish(b.topic("status"), payloadOnline)
+	if b.config.DiscoveryPrefix != "-" {
+		for id := range tvs {
+			b.publishDiscovery(id)
+		}
+	}
+	c.Subscribe(b.topic("+", "+", "set"), 1, b.command)
+
+	b.mu.Lock()
+	last := make([]*stateEvent, 0, len(b.last))
+	for _, ev := range b.last {
+		last = append(last, ev)
+	}
+	b.mu.Unlock()
+	for _, ev := range last {
+		b.publishState(ev)
+	}
+}
+
+func onOffPayload(on bool) string {
+	if on {
+		return payloadOn
+	}
+	return payloadOff
+}
+
+// supports reports whether a TV supports an attribute; TVs that cannot
+// report their capabilities are assumed to support everything.
+func supports(id int, attr tv.Attribute) bool {
+	c, ok := tvs[id].(tv.Capable)
+	if !ok {
+		return true
+	}
+	for _, capability := range c.Capabilities() {
+		if capability.Attribute == attr {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *mqttBridge) publishState(ev *stateEvent) {
+	name := tvName(ev.ID)
+	available := ev.Connection == "connected" || ev.Connection == "unknown" && ev.State != nil
+	if available {
+		b.publish(b.topic(name, "availability"), payloadOnline)
+	} else {
+		b.publish(b.topic(name, "availability"), payloadOffline)
+	}
+	if ev.State == nil {
+		return
+	}
+
+	for attr, v := range stateValues(ev.ID, ev.State) {
+		b.publish(b.topic(name, attr.String()), v)
+	}
+}
+
+// stateValues writes out each attribute of a TV's state that the TV
+// supports, as it would be written in a command.
+func stateValues(id int, state *tv.State) map[tv.Attribute]string {
+	values := map[tv.Attribute]string{
+		tv.Power:  onOffPayload(state.Power),
+		tv.Volume: strconv.Itoa(state.Volume),
+		tv.Mute:   onOffPayload(state.Mute),
+		tv.Screen: onOffPayload(state.Screen),
+		tv.Input:  fmt.Sprintf("%s %d", connectionNames[state.Input.Connection], state.Input.Number),
+	}
+	if state.Channel != nil {
+		values[tv.Tuning] = formatChannel(state.Channel)
+	}
+	for attr := range values {
+		if !supports(id, attr) {
+			delete(values, attr)
+		}
+	}
+	return values
+}
+
+// command performs a message sent to <prefix>/<tv>/<attribute>/set. Its
+// payload is what `avantgarde ctl` would take after the attribute, e.g.
+// "ON", "15", "+3" or "hdmi 2". No grant is checked; the broker's ACLs
+// decide who may send commands.
+func (b *mqttBridge) command(c mqtt.Client, m mqtt.Message) {
+	b.handlers.Add(1)
+	defer b.handlers.Done()
+	// This runs on paho's goroutine, where nothing else would catch a
+	// driver's panic before it took the whole server down.
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("mqtt: %s %q: panic: %v\n", m.Topic(), m.Payload(), r)
+		}
+	}()
+
+	parts := strings.Split(strings.TrimPrefix(m.Topic(), b.config.Prefix+"/"), "/")
+	if len(parts) != 3 {
+		return
+	}
+	id, ok := findTV(parts[0])
+	if !ok {
+		log.Printf("mqtt: %s: no such tv\n", m.Topic())
+		return
+	}
+	if parts[1] == tv.Raw.String() {
+		log.Printf("mqtt: %s: raw commands are not taken over MQTT\n", m.Topic())
+		return
+	}
+
+	a, err := ctlOp(parts[1], strings.Fields(string(m.Payload())))
+	var op *tv.Op
+	if err == nil {
+		op, err = a.toOp()
+	}
+	if err == nil {
+		err = checkSupported(tvs[id], op)
+	}
+	if err == nil {
+		err = doOp(tvs[id], op)
+	}
+	if err != nil {
+		log.Printf("mqtt: %s %q: %v\n", m.Topic(), m.Payload(), err)
+		return
+	}
+	hub.changed(id)
+}
+
+// haDevice and haEntity are the parts of a Home Assistant MQTT discovery
+// configuration that avantgarde fills in.
+type haDevice struct {
+	Identifiers  []string `json:"identifiers"`
+	Name         string   `json:"name"`
+	Model        string   `json:"model"`
+	Manufacturer string   `json:"manufacturer"`
+}
+
+type haAvailability struct {
+	Topic string `json:"topic"`
+}
+
+type haEntity struct {
+	Name             string           `json:"name"`
+	UniqueID         string           `json:"unique_id"`
+	Device           haDevice         `json:"device"`
+	Availability     []haAvailability `json:"availability"`
+	AvailabilityMode string           `json:"availability_mode"`
+	StateTopic       string           `json:"state_topic,omitempty"`
+	CommandTopic     string           `json:"command_topic,omitempty"`
+	PayloadOn        string           `json:"payload_on,omitempty"`
+	PayloadOff       string           `json:"payload_off,omitempty"`
+	Min              *int             `json:"min,omitempty"`
+	Max              *int             `json:"max,omitempty"`
+	// The media player carries volume and source topics besides.
+	VolumeStateTopic   string   `json:"volume_state_topic,omitempty"`
+	VolumeCommandTopic string   `json:"volume_command_topic,omitempty"`
+	SourceStateTopic   string   `json:"source_state_topic,omitempty"`
+	SourceCommandTopic string   `json:"source_command_topic,omitempty"`
+	SourceList         []string `json:"source_list,omitempty"`
+}
+
+// haNumbers are the attributes published as number entities.
+var haNumbers = []tv.Attribute{
+	tv.Volume, tv.Contrast, tv.Brightness, tv.Color, tv.Tint, tv.Sharpness,
+	tv.AudioBalance, tv.ColorTemperature, tv.Backlight,
+}
+
+// haSwitches are the attributes published as switch entities.
+var haSwitches = []tv.Attribute{tv.Power, tv.Mute, tv.Screen}
+
+// hasState reports whether the attribute is part of tv.State, and so has a
+// state topic.
+func hasState(attr tv.Attribute) bool {
+	switch attr {
+	case tv.Power, tv.Volume, tv.Mute, tv.Screen, tv.Input, tv.Tuning:
+		return true
+	}
+	return false
+}
+
+// haName turns an attribute into an entity name, like "Color temperature".
+func haName(attr tv.Attribute) string {
+	name := strings.ReplaceAll(attr.String(), "_", " ")
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+func (b *mqttBridge) discoveryTopic(component string, id int, object string) string {
+	node := b.config.ClientID + "_" + tvName(id)
+	return strings.Join([]string{b.config.DiscoveryPrefix, component, node, object, "config"}, "/")
+}
+
+// discovery builds the Home Assistant configurations for a TV, keyed by
+// their topics, from what the TV supports.
+func (b *mqttBridge) discovery(id int) map[string]*haEntity {
+	name := tvName(id)
+	node := b.config.ClientID + "_" + name
+	entity := func(attr tv.Attribute) *haEntity {
+		e := &haEntity{
+			Name:     haName(attr),
+			UniqueID: node + "_" + attr.String(),
+			Device: haDevice{
+				Identifiers:  []string{node},
+				Name:         name,
+				Model:        tvConfigs[id].V.Model,
+				Manufacturer: "avantgarde",
+			},
+			Availability: []haAvailability{
+				{b.topic("status")},
+				{b.topic(name, "availability")},
+			},
+			AvailabilityMode: "all",
+			CommandTopic:     b.topic(name, attr.String(), "set"),
+		}
+		if hasState(attr) {
+			e.StateTopic = b.topic(name, attr.String())
+		}
+		return e
+	}
+
+	caps := map[tv.Attribute]*tv.Capability{}
+	if c, ok := tvs[id].(tv.Capable); ok {
+		for _, capability := range c.Capabilities() {
+			capability := capability
+			caps[capability.Attribute] = &capability
+		}
+	} else {
+		for a := tv.Power; a <= tv.Raw; a++ {
+			caps[a] = &tv.Capability{Attribute: a}
+		}
+	}
+
+	configs := map[string]*haEntity{}
+	for _, attr := range haSwitches {
+		if caps[attr] == nil {
+			continue
+		}
+		e := entity(attr)
+		e.PayloadOn, e.PayloadOff = payloadOn, payloadOff
+		configs[b.discoveryTopic("switch", id, attr.String())] = e
+	}
+	for _, attr := range haNumbers {
+		c := caps[attr]
+		if c == nil {
+			continue
+		}
+		e := entity(attr)
+		lo, hi := 0, 100
+		if c.Max > c.Min {
+			lo, hi = c.Min, c.Max
+		}
+		e.Min, e.Max = &lo, &hi
+		configs[b.discoveryTopic("number", id, attr.String())] = e
+	}
+
+	if caps[tv.Power] != nil {
+		e := entity(tv.Power)
+		e.Name, e.UniqueID = name, node
+		e.PayloadOn, e.PayloadOff = payloadOn, payloadOff
+		if caps[tv.Volume] != nil {
+			e.VolumeStateTopic = b.topic(name, tv.Volume.String())
+			e.VolumeCommandTopic = b.topic(name, tv.Volume.String(), "set")
+		}
+		if c := caps[tv.Input]; c != nil {
+			e.SourceStateTopic = b.topic(name, tv.Input.String())
+			e.SourceCommandTopic = b.topic(name, tv.Input.String(), "set")
+			for _, i := range c.Inputs {
+				e.SourceList = append(e.SourceList, fmt.Sprintf("%s %d", connectionNames[i.Connection], i.Number))
+			}
+		}
+		configs[b.discoveryTopic("media_player", id, "tv")] = e
+	}
+	return configs
+}
+
+func (b *mqttBridge) publishDiscovery(id int) {
+	for topic, e := range b.discovery(id) {
+		payload, _ := json.Marshal(e)
+		b.publish(topic, payload)
+	}
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eclipse/paho.mqtt.golang/packets"
+
+	"github.com/DHowett/avantgarde/tv"
+)
+
+// testBroker is just enough of an MQTT broker for the bridge: it keeps
+// retained messages, matches + and # in subscriptions, and publishes a
+// client's will when its connection drops without a DISCONNECT.
+type testBroker struct {
+	ln net.Listener
+
+	mu       sync.Mutex
+	retained map[string][]byte
+	subs     map[net.Conn][]string
+	clients  map[string]net.Conn
+}
+
+func newTestBroker(t *testing.T) *testBroker {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &testBroker{
+		ln:       ln,
+		retained: map[string][]byte{},
+		subs:     map[net.Conn][]string{},
+		clients:  map[string]net.Conn{},
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { ln.Close() })
+	return b
+}
+
+func (b *testBroker) url() string {
+	return "tcp://" + b.ln.Addr().String()
+}
+
+func topicMatches(filter, topic string) bool {
+	f, t := strings.Split(filter, "/"), strings.Split(topic, "/")
+	for i, part := range f {
+		if part == "#" {
+			return true
+		}
+		if i >= len(t) || part != "+" && part != t[i] {
+			return false
+		}
+	}
+	return len(f) == len(t)
+}
+
+func (b *testBroker) publish(topic string, payload []byte, retain bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if retain {
+		b.retained[topic] = payload
+	}
+	for conn, filters := range b.subs {
+		for _, f := range filters {
+			if topicMatches(f, topic) {
+				p := packets.NewControlPacket(packets.Publish).(*packets.PublishPacket)
+				p.TopicName, p.Payload = topic, payload
+				p.Write(conn)
+				break
+			}
+		}
+	}
+}
+
+// drop cuts a client off as if its network had failed.
+func (b *testBroker) drop(clientID string) {
+	b.mu.Lock()
+	conn := b.clients[clientID]
+	b.mu.Unlock()
+	if conn != nil {
+		conn.Close()
+	}
+}
+
+func (b *testBroker) serve(conn net.Conn) {
+	var will *packets.ConnectPacket
+	defer func() {
+		b.mu.Lock()
+		delete(b.subs, conn)
+		b.mu.Unlock()
+		conn.Close()
+		if will != nil {
+			b.publish(will.WillTopic, will.WillMessage, will.WillRetain)
+		}
+	}()
+
+	for {
+		cp, err := packets.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+		switch p := cp.(type) {
+		case *packets.ConnectPacket:
+			if p.WillFlag {
+				will = p
+			}
+			b.mu.Lock()
+			b.clients[p.ClientIdentifier] = conn
+			b.mu.Unlock()
+			packets.NewControlPacket(packets.Connack).Write(conn)
+		case *packets.SubscribePacket:
+			ack := packets.NewControlPacket(packets.Suback).(*packets.SubackPacket)
+			ack.MessageID = p.MessageID
+			ack.ReturnCodes = make([]byte, len(p.Topics))
+			b.mu.Lock()
+			b.subs[conn] = append(b.subs[conn], p.Topics...)
+			b.mu.Unlock()
+			ack.Write(conn)
+			b.mu.Lock()
+			for topic, payload := range b.retained {
+				for _, f := range p.Topics {
+					if topicMatches(f, topic) {
+						m := packets.NewControlPacket(packets.Publish).(*packets.PublishPacket)
+						m.TopicName, m.Payload, m.Retain = topic, payload, true
+						m.Write(conn)
+						break
+					}
+				}
+			}
+			b.mu.Unlock()
+		case *packets.PublishPacket:
+			if p.Qos > 0 {
+				ack := packets.NewControlPacket(packets.Puback).(*packets.PubackPacket)
+				ack.MessageID = p.MessageID
+				ack.Write(conn)
+			}
+			b.publish(p.TopicName, p.Payload, p.Retain)
+		case *packets.PingreqPacket:
+			packets.NewControlPacket(packets.Pingresp).Write(conn)
+		case *packets.DisconnectPacket:
+			will = nil
+			return
+		}
+	}
+}
+
+// waitFor polls the broker's retained messages until topic holds want.
+func (b *testBroker) waitFor(t *testing.T, topic, want string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		b.mu.Lock()
+		got, ok := b.retained[topic]
+		b.mu.Unlock()
+		if ok && string(got) == want {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t.Fatalf("Got %q instead of %q at %s!", b.retained[topic], want, topic)
+}
+
+func (b *testBroker) retainedMessage(topic string) []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.retained[topic]
+}
+
+// offlineTV is a fake whose driver has lost its connection.
+type offlineTV struct{ *fakeTV }
+
+func (offlineTV) Connected() bool { return false }
+
+func TestMQTTBridge(t *testing.T) {
+	fake := &fakeTV{}
+	defer useFakeTVs(fake, offlineTV{&fakeTV{}})()
+	defer useTestHub()()
+	broker := newTestBroker(t)
+
+	bridge := newMQTTBridge(MQTTConfig{Broker: broker.url()})
+	bridge.start(hub)
+
+	broker.waitFor(t, "avantgarde/status", "online")
+	broker.waitFor(t, "avantgarde/fake0/availability", "online")
+	broker.waitFor(t, "avantgarde/fake0/volume", "15")
+	broker.waitFor(t, "avantgarde/fake1/availability", "offline")
+
+	// The fake TV supports volume and input, but not the screen.
+	var volume haEntity
+	if err := json.Unmarshal(broker.retainedMessage("homeassistant/number/avantgarde_fake0/volume/config"), &volume); err != nil {
+		t.Fatalf("Failed to parse the volume's discovery config: %v", err)
+	}
+	if volume.CommandTopic != "avantgarde/fake0/volume/set" || volume.StateTopic != "avantgarde/fake0/volume" || *volume.Max != 100 {
+		t.Errorf("Got %+v for the volume's discovery config!", volume)
+	}
+	if len(volume.Availability) != 2 || volume.AvailabilityMode != "all" {
+		t.Errorf("Got availability %+v, not the bridge's and the TV's!", volume.Availability)
+	}
+	if broker.retainedMessage("homeassistant/switch/avantgarde_fake0/screen/config") != nil {
+		t.Errorf("The screen was announced for a TV that does not support it!")
+	}
+
+	// The fake cannot toggle its power, so that is refused.
+	broker.publish("avantgarde/fake0/power/set", []byte("toggle"), false)
+	broker.publish("avantgarde/fake0/volume/set", []byte("20"), false)
+	broker.publish("avantgarde/fake0/input/set", []byte("hdmi 1"), false)
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		fake.mu.Lock()
+		n := len(fake.ops)
+		fake.mu.Unlock()
+		if n == 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	fake.mu.Lock()
+	var got []tv.Op
+	for _, op := range fake.ops {
+		got = append(got, *op)
+	}
+	fake.mu.Unlock()
+	if expect := []tv.Op{{tv.Volume, tv.Set, 20}, {tv.Input, tv.Set, tv.InputNumber{tv.HDMI, 1}}}; fmt.Sprint(got) != fmt.Sprint(expect) {
+		t.Errorf("Got %v instead of %v!", got, expect)
+	}
+
+	// If the bridge vanishes, the broker marks it offline.
+	broker.drop("avantgarde")
+	broker.waitFor(t, "avantgarde/status", "offline")
+	// ... until it reconnects.
+	broker.waitFor(t, "avantgarde/status", "online")
+
+	bridge.stop()
+	broker.waitFor(t, "avantgarde/status", "offline")
+}
+
+// testMessage is a message as paho hands it to a subscription's handler.
+type testMessage struct {
+	topic   string
+	payload []byte
+}
+
+func (m *testMessage) Duplicate() bool   { return false }
+func (m *testMessage) Qos() byte         { return 0 }
+func (m *testMessage) Retained() bool    { return false }
+func (m *testMessage) Topic() string     { return m.topic }
+func (m *testMessage) MessageID() uint16 { return 0 }
+func (m *testMessage) Payload() []byte   { return m.payload }
+func (m *testMessage) Ack()              {}
+
+// panickingTV is a driver with a bug.
+type panickingTV struct {
+	*fakeTV
+}
+
+func (panickingTV) Do(op *tv.Op) error {
+	panic("fake: bug")
+}
+
+func TestMQTTCommandPanic(t *testing.T) {
+	defer useFakeTVs(panickingTV{&fakeTV{}})()
+	bridge := newMQTTBridge(MQTTConfig{Broker: "tcp://127.0.0.1:1"})
+
+	// A driver's panic is logged rather than taking the server down.
+	bridge.command(nil, &testMessage{"avantgarde/fake0/volume/set", []byte("20")})
+}
@@ -0,0 +1,481 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/DHowett/avantgarde/tv"
+)
+
+// route describes an endpoint registered on tvServer, so that the server
+// can document itself.
+type route struct {
+	path, method string
+	summary      string
+	// attr is the attribute the endpoint acts upon, or 0 for endpoints,
+	// like /status, that are not tied to one.
+	attr   tv.Attribute
+	params []*param
+	// supports reports whether a TV can be served at this endpoint. If it
+	// is nil, the TV's capabilities decide.
+	supports func(tv.TV) bool
+}
+
+func (rt *route) describe(attr tv.Attribute, summary string, params ...*param) *route {
+	rt.attr = attr
+	rt.summary = summary
+	rt.params = params
+	return rt
+}
+
+func (rt *route) supportedIf(supports func(tv.TV) bool) *route {
+	rt.supports = supports
+	return rt
+}
+
+// capability returns what a TV supports of the route's attribute, and
+// whether the route applies to it at all. TVs that cannot report their
+// capabilities are assumed to support everything.
+func (rt *route) capability(t tv.TV) (*tv.Capability, bool) {
+	if rt.supports != nil {
+		return nil, rt.supports(t)
+	}
+	c, ok := t.(tv.Capable)
+	if rt.attr == 0 || !ok {
+		return nil, true
+	}
+	for _, capability := range c.Capabilities() {
+		if capability.Attribute == rt.attr {
+			return &capability, true
+		}
+	}
+	return nil, false
+}
+
+// param is a form field taken by an endpoint.
+type param struct {
+	name   string
+	schema *schema
+}
+
+func boolParam(name, description string) *param {
+	return &param{name, &schema{Type: "string", Description: description, Enum: []string{"0", "1"}}}
+}
+
+// intParam describes an integer field; a max of 0 leaves it unbounded.
+func intParam(name, description string, min, max int) *param {
+	s := &schema{Type: "integer", Description: description, Minimum: &min}
+	if max != 0 {
+		s.Maximum = &max
+	}
+	return &param{name, s}
+}
+
+func stringParam(name, description string) *param {
+	return &param{name, &schema{Type: "string", Description: description}}
+}
+
+func enumParam(name, description string, values ...string) *param {
+	return &param{name, &schema{Type: "string", Description: description, Enum: values}}
+}
+
+// volumeParam describes a level from 0 to 100 that may also be given as
+// "min" or "max".
+func volumeParam(name, description string) *param {
+	min, max := 0, 100
+	return &param{name, &schema{Description: description, OneOf: []*schema{
+		{Type: "integer", Minimum: &min, Maximum: &max},
+		{Type: "string", Enum: []string{"min", "max"}},
+	}}}
+}
+
+type schema struct {
+	Type        string             `json:"type,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Enum        []string           `json:"enum,omitempty"`
+	Minimum     *int               `json:"minimum,omitempty"`
+	Maximum     *int               `json:"maximum,omitempty"`
+	OneOf       []*schema          `json:"oneOf,omitempty"`
+	Properties  map[string]*schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Items       *schema            `json:"items,omitempty"`
+}
+
+// narrow returns a copy of s restricted to what a TV supports: integer
+// ranges are taken from the capability, and connection names are limited
+// to those of the TV's inputs.
+func (s *schema) narrow(c *tv.Capability) *schema {
+	if c == nil {
+		return s
+	}
+	n := *s
+	if n.Type == "integer" && c.Max > c.Min {
+		lo, hi := c.Min, c.Max
+		n.Minimum, n.Maximum = &lo, &hi
+	}
+	if c.Attribute == tv.Input && len(c.Inputs) > 0 && n.Enum != nil {
+		n.Enum = nil
+		seen := map[string]bool{}
+		for _, i := range c.Inputs {
+			name := connectionNames[i.Connection]
+			if !seen[name] {
+				seen[name] = true
+				n.Enum = append(n.Enum, name)
+			}
+		}
+	}
+	if n.OneOf != nil {
+		n.OneOf = make([]*schema, len(s.OneOf))
+		for i, o := range s.OneOf {
+			n.OneOf[i] = o.narrow(c)
+		}
+	}
+	return &n
+}
+
+type mediaType struct {
+	Schema *schema `json:"schema"`
+}
+
+type requestBody struct {
+	Content map[string]mediaType `json:"content"`
+}
+
+type response struct {
+	Description string               `json:"description"`
+	Content     map[string]mediaType `json:"content,omitempty"`
+}
+
+type parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *schema `json:"schema"`
+}
+
+type operation struct {
+	Summary     string               `json:"summary,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []*parameter         `json:"parameters,omitempty"`
+	RequestBody *requestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*response `json:"responses"`
+}
+
+type openAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type openAPIDocument struct {
+	OpenAPI string                           `json:"openapi"`
+	Info    openAPIInfo                      `json:"info"`
+	Paths   map[string]map[string]*operation `json:"paths"`
+}
+
+func jsonContent(s *schema) map[string]mediaType {
+	return map[string]mediaType{"application/json": {s}}
+}
+
+var (
+	errorSchema = &schema{
+		Type: "object",
+		Properties: map[string]*schema{
+			"error": {
+				Type: "object",
+				Properties: map[string]*schema{
+					"code":    {Type: "string", Enum: []string{codeInvalidRequest, codeInvalidValue, codeNotFound, codeMethodNotAllowed, codeUnsupported, codeTimeout, codeDisconnected, codeInternal, codeUnauthorized, codeForbidden}},
+					"message": {Type: "string"},
+				},
+			},
+		},
+	}
+	stateSchema = &schema{Type: "object", Description: "the TV's state"}
+)
+
+func formOperation(rt *route, c *tv.Capability) *operation {
+	op := &operation{
+		Summary: rt.summary,
+		Responses: map[string]*response{
+			"400": {Description: "a parameter is missing or malformed"},
+			"500": {Description: "the TV failed to carry out the command"},
+		},
+	}
+	if auth != nil {
+		op.Responses["401"] = &response{Description: "no valid token was given"}
+		op.Responses["403"] = &response{Description: "the token does not grant access"}
+	}
+	if rt.method == "GET" {
+		op.Responses["200"] = &response{Description: "OK", Content: jsonContent(&schema{Type: "object"})}
+		if rt.path == "/status" {
+			op.Responses["200"].Content = jsonContent(stateSchema)
+		}
+		return op
+	}
+
+	op.Responses["204"] = &response{Description: "done"}
+	if rt.attr == tv.Raw {
+		op.Responses["200"] = &response{Description: "the TV's reply"}
+		op.Responses["403"] = &response{Description: "raw commands are disabled for this TV"}
+	}
+	if len(rt.params) > 0 {
+		form := &schema{Type: "object", Properties: map[string]*schema{}}
+		for _, p := range rt.params {
+			form.Properties[p.name] = p.schema.narrow(c)
+		}
+		op.RequestBody = &requestBody{map[string]mediaType{"application/x-www-form-urlencoded": {form}}}
+	}
+	return op
+}
+
+func attributeSchema() *schema {
+	s := &schema{Type: "string"}
+	for a := tv.Power; a <= tv.Raw; a++ {
+		s.Enum = append(s.Enum, a.String())
+	}
+	return s
+}
+
+func operatorSchema() *schema {
+	s := &schema{Type: "string"}
+	for o := tv.Set; o <= tv.Query; o++ {
+		s.Enum = append(s.Enum, o.String())
+	}
+	return s
+}
+
+// apiPaths documents the JSON API, which addresses TVs by a path parameter.
+func apiPaths() map[string]map[string]*operation {
+	var names []string
+	for i := range tvs {
+		if name := tvConfigs[i].V.Name; name != "" {
+			names = append(names, name)
+		}
+	}
+	id := &schema{Type: "string", Description: "the TV's name or number; the TVs are named " + strings.Join(names, ", ")}
+	idParam := []*parameter{{"id", "path", true, id}}
+	withErrors := func(op *operation) *operation {
+		for _, status := range []int{400, 401, 403, 404, 500, 501, 503, 504} {
+			op.Responses[strconv.Itoa(status)] = &response{Description: http.StatusText(status), Content: jsonContent(errorSchema)}
+		}
+		return op
+	}
+	list := &operation{
+		Summary:   "List every TV with its capabilities",
+		Tags:      []string{"api"},
+		Responses: map[string]*response{"200": {Description: "OK", Content: jsonContent(&schema{Type: "array", Items: &schema{Type: "object"}})}},
+	}
+
+	return map[string]map[string]*operation{
+		"/tvs":        {"get": list},
+		"/api/v1/tvs": {"get": list},
+		"/api/v1/tv/{id}/op": {"post": withErrors(&operation{
+			Summary:    "Perform an operation",
+			Tags:       []string{"api"},
+			Parameters: idParam,
+			RequestBody: &requestBody{jsonContent(&schema{
+				Type: "object",
+				Properties: map[string]*schema{
+					"attribute": attributeSchema(),
+					"operator":  operatorSchema(),
+					"value":     {Description: "a boolean, integer, channel string, raw command string or {connection, number} input, as the attribute requires"},
+				},
+				Required: []string{"attribute"},
+			})},
+			Responses: map[string]*response{
+				"204": {Description: "done"},
+				"200": {Description: "the reply to a raw command", Content: jsonContent(&schema{
+					Type:       "object",
+					Properties: map[string]*schema{"reply": {Type: "string"}},
+				})},
+			},
+		})},
+		"/api/v1/tv/{id}/state": {"get": withErrors(&operation{
+			Summary:    "Read the TV's state",
+			Tags:       []string{"api"},
+			Parameters: idParam,
+			Responses:  map[string]*response{"200": {Description: "OK", Content: jsonContent(stateSchema)}},
+		})},
+		"/openapi.json": {"get": {
+			Summary:   "Describe every endpoint, as this document does",
+			Tags:      []string{"api"},
+			Responses: map[string]*response{"200": {Description: "OK", Content: jsonContent(&schema{Type: "object"})}},
+		}},
+		"/events": {"get": {
+			Summary: "Follow the state of every TV as server-sent events",
+			Tags:    []string{"api"},
+			Responses: map[string]*response{
+				"200": {Description: `an "event: state" for the last known state of every TV, then one for each change`, Content: map[string]mediaType{
+					"text/event-stream": {&schema{Type: "string"}},
+				}},
+				"401": {Description: "no valid token was given"},
+				"503": {Description: "state is not being followed"},
+			},
+		}},
+		"/scenes": {"get": {
+			Summary: "List the scenes the token may run",
+			Tags:    []string{"scenes"},
+			Responses: map[string]*response{
+				"200": {Description: "OK", Content: jsonContent(&schema{Type: "array", Items: &schema{Type: "string"}})},
+				"401": {Description: "no valid token was given", Content: jsonContent(errorSchema)},
+			},
+		}},
+		"/scenes/{name}": {"post": {
+			Summary:    "Run a scene, answering once it has finished",
+			Tags:       []string{"scenes"},
+			Parameters: []*parameter{{"name", "path", true, &schema{Type: "string", Description: "the scene's name"}}},
+			Responses: map[string]*response{
+				"200": {Description: "a result for every step on every TV", Content: jsonContent(&schema{
+					Type: "object",
+					Properties: map[string]*schema{
+						"scene":  {Type: "string"},
+						"status": {Type: "string", Enum: []string{stepDone, stepFailed}},
+						"results": {Type: "array", Items: &schema{
+							Type: "object",
+							Properties: map[string]*schema{
+								"step":   {Type: "string"},
+								"action": {Type: "string"},
+								"tv":     {Type: "string"},
+								"status": {Type: "string", Enum: []string{stepDone, stepSkipped, stepFailed, stepNotRun}},
+								"error":  errorSchema.Properties["error"],
+							},
+						}},
+					},
+				})},
+				"401": {Description: "no valid token was given", Content: jsonContent(errorSchema)},
+				"403": {Description: "the token does not grant everything the scene does", Content: jsonContent(errorSchema)},
+				"404": {Description: "there is no such scene", Content: jsonContent(errorSchema)},
+			},
+		}},
+	}
+}
+
+var groupParam = &parameter{"name", "path", true, &schema{Type: "string", Description: "the group's name"}}
+
+// groupOperation documents a form endpoint as served for a group, which
+// answers with every member's response.
+func groupOperation(rt *route) *operation {
+	op := &operation{
+		Summary:    rt.summary + ", on every TV in the group",
+		Tags:       []string{"groups"},
+		Parameters: []*parameter{groupParam},
+		Responses: map[string]*response{
+			"200": {Description: "each TV's status and body, as it would have answered alone", Content: jsonContent(&schema{
+				Type: "object",
+				Properties: map[string]*schema{
+					"group":  {Type: "string"},
+					"status": {Type: "string", Enum: []string{stepDone, stepFailed}},
+					"results": {Type: "array", Items: &schema{
+						Type: "object",
+						Properties: map[string]*schema{
+							"tv":     {Type: "string"},
+							"status": {Type: "integer"},
+							"body":   {Description: "the TV's response; JSON as it was, or else a string"},
+						},
+					}},
+				},
+			})},
+			"401": {Description: "no valid token was given", Content: jsonContent(errorSchema)},
+			"403": {Description: "the token does not grant this on every TV in the group", Content: jsonContent(errorSchema)},
+			"404": {Description: "there is no such group", Content: jsonContent(errorSchema)},
+		},
+	}
+	if len(rt.params) > 0 {
+		op.RequestBody = formOperation(rt, nil).RequestBody
+	}
+	return op
+}
+
+// groupPaths documents the group endpoints, which serve every form
+// endpoint under /group/{name}.
+func (sv *tvServer) groupPaths() map[string]map[string]*operation {
+	paths := map[string]map[string]*operation{
+		"/groups": {"get": {
+			Summary: "List the groups the token may use, with their members",
+			Tags:    []string{"groups"},
+			Responses: map[string]*response{
+				"200": {Description: "OK", Content: jsonContent(&schema{Type: "object", Description: "each group's members, by the group's name"})},
+				"401": {Description: "no valid token was given", Content: jsonContent(errorSchema)},
+			},
+		}},
+		"/group/{name}/state": {"get": {
+			Summary:    "Read every member's state, setting out where they disagree",
+			Tags:       []string{"groups"},
+			Parameters: []*parameter{groupParam},
+			Responses: map[string]*response{
+				"200": {Description: "OK", Content: jsonContent(&schema{
+					Type: "object",
+					Properties: map[string]*schema{
+						"group":     {Type: "string"},
+						"agreed":    {Type: "object", Description: "each attribute every member shows alike, written as for ctl"},
+						"disagreed": {Type: "object", Description: "for every other attribute, what each member shows"},
+						"members":   {Type: "array", Items: &schema{Type: "object"}},
+					},
+				})},
+				"401": {Description: "no valid token was given", Content: jsonContent(errorSchema)},
+				"403": {Description: "the token does not grant every TV in the group", Content: jsonContent(errorSchema)},
+				"404": {Description: "there is no such group", Content: jsonContent(errorSchema)},
+			},
+		}},
+	}
+	for _, rt := range sv.routes {
+		path := "/group/{name}" + rt.path
+		if paths[path] == nil {
+			paths[path] = map[string]*operation{}
+		}
+		paths[path][strings.ToLower(rt.method)] = groupOperation(rt)
+	}
+	return paths
+}
+
+// openAPI documents every endpoint registered on the server. Form endpoints
+// are listed separately for each TV, and only where the TV supports them,
+// with parameter ranges narrowed to what it accepts.
+func (sv *tvServer) openAPI() *openAPIDocument {
+	doc := &openAPIDocument{
+		OpenAPI: "3.0.3",
+		Info: openAPIInfo{
+			Title:       "avantgarde",
+			Description: "TVs are listed by name; each is also available by its position in the configuration.",
+			Version:     "1",
+		},
+		Paths: apiPaths(),
+	}
+	for path, ops := range sv.groupPaths() {
+		doc.Paths[path] = ops
+	}
+
+	for i, t := range tvs {
+		id := tvConfigs[i].V.Name
+		if id == "" {
+			id = fmt.Sprint(i)
+		}
+		for _, rt := range sv.routes {
+			c, ok := rt.capability(t)
+			if !ok || rt.attr == tv.Raw && !rawAllowed(i) {
+				continue
+			}
+			op := formOperation(rt, c)
+			op.Tags = []string{id}
+			path := "/tv/" + id + rt.path
+			if doc.Paths[path] == nil {
+				doc.Paths[path] = map[string]*operation{}
+			}
+			doc.Paths[path][strings.ToLower(rt.method)] = op
+		}
+	}
+	return doc
+}
+
+func (sv *tvServer) serveOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if _, ok := auth.authenticate(w, r, plainFailure(w)); !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, sv.openAPI())
+}
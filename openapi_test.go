@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DHowett/avantgarde/tv"
+)
+
+func TestOpenAPI(t *testing.T) {
+	defer useFakeTVs(&fakeTV{})()
+
+	sv := newTVServer()
+	w := httptest.NewRecorder()
+	sv.serveOpenAPI(w, httptest.NewRequest("GET", "/openapi.json", nil))
+
+	var doc openAPIDocument
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("Failed to parse the document: %v", err)
+	}
+
+	for _, path := range []string{"/tv/fake0/status", "/tv/fake0/volume", "/tv/fake0/input", "/api/v1/tv/{id}/op"} {
+		if doc.Paths[path] == nil {
+			t.Errorf("%s is missing!", path)
+		}
+	}
+	// The fake TV does not support these.
+	for _, path := range []string{"/tv/fake0/screen", "/tv/fake0/route", "/tv/fake0/pair"} {
+		if doc.Paths[path] != nil {
+			t.Errorf("%s is documented for a TV that does not support it!", path)
+		}
+	}
+
+	volume := doc.Paths["/tv/fake0/volume"]["post"].RequestBody.Content["application/x-www-form-urlencoded"].Schema
+	if v := volume.Properties["v"].OneOf[0]; *v.Minimum != 0 || *v.Maximum != 100 {
+		t.Errorf("Got volume range %d-%d instead of 0-100!", *v.Minimum, *v.Maximum)
+	}
+	if d := volume.Properties["d"]; len(d.Enum) != 2 {
+		t.Errorf("Got volume directions %v instead of up and down!", d.Enum)
+	}
+
+	input := doc.Paths["/tv/fake0/input"]["post"].RequestBody.Content["application/x-www-form-urlencoded"].Schema
+	if c := input.Properties["c"]; len(c.Enum) != 1 || c.Enum[0] != "hdmi" {
+		t.Errorf("Got connections %v instead of just hdmi!", c.Enum)
+	}
+	if input.Properties["n"] == nil {
+		t.Errorf("The input number is not documented!")
+	}
+}
+
+func TestOpenAPICoversHandlers(t *testing.T) {
+	defer useFakeTVs(&fakeTV{})()
+
+	sv := newTVServer()
+	doc := sv.openAPI()
+	for pattern := range handlers(sv) {
+		if pattern == "/" {
+			// The remote control page is not part of the API.
+			continue
+		}
+		found := false
+		for path := range doc.Paths {
+			if path == pattern || strings.HasSuffix(pattern, "/") && strings.HasPrefix(path, pattern) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("%s is served but not documented!", pattern)
+		}
+	}
+}
+
+func TestOpenAPIWithoutCapabilities(t *testing.T) {
+	// A TV that cannot report its capabilities is documented for every
+	// attribute endpoint.
+	defer useFakeTVs(struct{ tv.TV }{&fakeTV{}})()
+	tvConfigs[0].V.AllowRaw = true
+
+	doc := newTVServer().openAPI()
+	if doc.Paths["/tv/fake0/power"] == nil || doc.Paths["/tv/fake0/raw"] == nil {
+		t.Errorf("Endpoints were left out for a TV without capabilities!")
+	}
+	if doc.Paths["/tv/fake0/route"] != nil {
+		t.Errorf("/route is documented for a TV that is not a switcher!")
+	}
+}
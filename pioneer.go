@@ -2,22 +2,38 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/jessevdk/go-flags"
+	"github.com/tarm/serial"
 	"gopkg.in/yaml.v2"
 
 	"github.com/DHowett/avantgarde/tv"
+	_ "github.com/DHowett/avantgarde/tv/cec"
+	_ "github.com/DHowett/avantgarde/tv/denon"
+	_ "github.com/DHowett/avantgarde/tv/generic"
 	_ "github.com/DHowett/avantgarde/tv/lg"
+	_ "github.com/DHowett/avantgarde/tv/lgwebos"
+	_ "github.com/DHowett/avantgarde/tv/matrix"
+	_ "github.com/DHowett/avantgarde/tv/onkyo"
+	_ "github.com/DHowett/avantgarde/tv/philips"
+	_ "github.com/DHowett/avantgarde/tv/roku"
+	_ "github.com/DHowett/avantgarde/tv/sharp"
 	_ "github.com/DHowett/avantgarde/tv/sony"
+	_ "github.com/DHowett/avantgarde/tv/vizio"
 )
 
 var inputNameToTV = map[string]tv.Connection{
@@ -68,92 +84,330 @@ func ParseChannel(s string) (tv.Channel, error) {
 	return tv.AnalogChannel(uint(ch)), nil
 }
 
+type contextKey int
+
+const tvContextKey contextKey = 0
+
+// requestTVID returns the index of the TV that tvServer resolved for a
+// request.
+func requestTVID(r *http.Request) int {
+	return r.Context().Value(tvContextKey).(int)
+}
+
+// requestTV returns the TV that tvServer resolved for a request.
+func requestTV(r *http.Request) tv.TV {
+	return tvs[requestTVID(r)]
+}
+
 type tvServer struct {
-	reqTv map[*http.Request]int
-	mux   *http.ServeMux
+	mux    *http.ServeMux
+	routes []*route
+}
+
+func isSwitcher(t tv.TV) bool {
+	_, ok := t.(tv.Switcher)
+	return ok
+}
+
+func isPairer(t tv.TV) bool {
+	_, ok := t.(tv.Pairer)
+	return ok
 }
 
 func newTVServer() *tvServer {
-	sv := &tvServer{make(map[*http.Request]int), http.NewServeMux()}
-	sv.mux.Handle("/status", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "OPTIONS, GET")
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
+	sv := &tvServer{mux: http.NewServeMux()}
+	sv.handle("/status", "GET", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		state, err := requestTV(r).State()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
-		if r.Method != "GET" {
-			w.WriteHeader(http.StatusMethodNotAllowed)
+		enc := json.NewEncoder(w)
+		err = enc.Encode(state)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	})).
+		describe(0, "Read the TV's state")
+	sv.handle("/route", "POST", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw, ok := requestTV(r).(tv.Switcher)
+		if !ok {
+			w.WriteHeader(http.StatusNotImplemented)
+			return
+		}
+
+		input, err := strconv.Atoi(r.FormValue("i"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		output, err := strconv.Atoi(r.FormValue("o"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
 
-		tvId := sv.reqTv[r]
-		state, err := tvs[tvId].State()
+		err = sw.Route(input, output)
+		if err != nil {
+			_, status := classifyError(err)
+			w.WriteHeader(status)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})).
+		describe(0, "Send a switcher input to an output",
+			intParam("i", "input, numbered from 1", 1, 0),
+			intParam("o", "output, numbered from 1", 1, 0)).
+		supportedIf(isSwitcher)
+	sv.handle("/routes", "GET", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw, ok := requestTV(r).(tv.Switcher)
+		if !ok {
+			w.WriteHeader(http.StatusNotImplemented)
+			return
+		}
+
+		routes, err := sw.Routes()
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 		enc := json.NewEncoder(w)
-		err = enc.Encode(state)
+		err = enc.Encode(routes)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
-	}))
+	})).
+		describe(0, "List the input shown on each of a switcher's outputs").
+		supportedIf(isSwitcher)
+	sv.handle("/pair", "POST", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pairer, ok := requestTV(r).(tv.Pairer)
+		if !ok {
+			w.WriteHeader(http.StatusNotImplemented)
+			return
+		}
+
+		// Without a PIN, ask the TV to display one.
+		var err error
+		if pin := r.FormValue("v"); pin == "" {
+			err = pairer.BeginPairing()
+		} else {
+			err = pairer.FinishPairing(pin)
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})).
+		describe(0, "Pair with the TV; without v, ask it to show a PIN",
+			stringParam("v", "the PIN the TV shows")).
+		supportedIf(isPairer)
+	sv.bindCommand("/mute", &tv.Op{tv.Mute, tv.Set, true}).
+		describe(tv.Mute, "Mute the TV")
+	sv.bindCommand("/unmute", &tv.Op{tv.Mute, tv.Set, false}).
+		describe(tv.Mute, "Unmute the TV")
+	sv.bindCommandGenerator("/power", boolGenerator("v", tv.Power)).
+		describe(tv.Power, "Turn the TV on or off", boolParam("v", "1 for on"))
+	sv.bindCommandGenerator("/screen", boolGenerator("v", tv.Screen)).
+		describe(tv.Screen, "Blank or show the picture", boolParam("v", "1 to show the picture"))
+	sv.bindCommandGenerator("/osd", boolGenerator("v", tv.OSD)).
+		describe(tv.OSD, "Show or hide the on-screen display", boolParam("v", "1 to show the OSD"))
+	sv.bindCommandGenerator("/volume", func(r *http.Request) *tv.Op {
+		dir := r.FormValue("d")
+		formV := r.FormValue("v")
+		if formV == "max" {
+			return &tv.Op{tv.Volume, tv.Set, 100}
+		} else if formV == "min" {
+			return &tv.Op{tv.Volume, tv.Set, 0}
+		}
+
+		val, e := strconv.Atoi(formV)
+		if e != nil {
+			return nil
+		}
+		if dir == "up" {
+			return &tv.Op{tv.Volume, tv.Increment, 1}
+		} else if dir == "down" {
+			return &tv.Op{tv.Volume, tv.Decrement, 1}
+		} else {
+			return &tv.Op{tv.Volume, tv.Set, val}
+		}
+	}).
+		describe(tv.Volume, "Set the volume, or step it up or down",
+			volumeParam("v", "volume, or min or max"),
+			enumParam("d", "step the volume instead of setting it", "up", "down"))
+	sv.bindCommandGenerator("/input", func(r *http.Request) *tv.Op {
+		connectionName := r.FormValue("c")
+		connectionNumberS := r.FormValue("n")
+		if connectionName == "" || connectionNumberS == "" {
+			return nil
+		}
+		connectionNumber, err := strconv.Atoi(connectionNumberS)
+		if err != nil {
+			return nil
+		}
+
+		connection, ok := inputNameToTV[connectionName]
+		if !ok {
+			return nil
+		}
+		return &tv.Op{tv.Input, tv.Set, tv.InputNumber{connection, connectionNumber}}
+
+	}).
+		describe(tv.Input, "Switch inputs",
+			enumParam("c", "connection", tvInputNames...),
+			intParam("n", "input number, among inputs with this connection", 1, 0))
+	sv.bindCommandGenerator("/contrast", intGenerator("v", tv.Contrast)).
+		describe(tv.Contrast, "Set the contrast", intParam("v", "contrast", 0, 100))
+	sv.bindCommandGenerator("/brightness", intGenerator("v", tv.Brightness)).
+		describe(tv.Brightness, "Set the brightness", intParam("v", "brightness", 0, 100))
+	sv.bindCommandGenerator("/color", intGenerator("v", tv.Color)).
+		describe(tv.Color, "Set the color", intParam("v", "color", 0, 100))
+	sv.bindCommandGenerator("/tint", intGenerator("v", tv.Tint)).
+		describe(tv.Tint, "Set the tint", intParam("v", "tint", 0, 100))
+	sv.bindCommandGenerator("/sharpness", intGenerator("v", tv.Sharpness)).
+		describe(tv.Sharpness, "Set the sharpness", intParam("v", "sharpness", 0, 100))
+	sv.bindCommandGenerator("/balance", intGenerator("v", tv.AudioBalance)).
+		describe(tv.AudioBalance, "Set the audio balance", intParam("v", "audio balance", 0, 100))
+	sv.bindCommandGenerator("/color_temperature", intGenerator("v", tv.ColorTemperature)).
+		describe(tv.ColorTemperature, "Set the color temperature", intParam("v", "color temperature", 0, 100))
+	sv.bindCommandGenerator("/backlight", intGenerator("v", tv.Backlight)).
+		describe(tv.Backlight, "Set the backlight", intParam("v", "backlight", 0, 100))
+	sv.bindCommandGenerator("/channel", func(r *http.Request) *tv.Op {
+		ch, err := ParseChannel(r.FormValue("v"))
+		if err != nil {
+			return nil
+		}
+		/*
+			antenna := r.FormValue("a")
+			if antenna == "" {
+				return nil
+			}
+		*/
+		return &tv.Op{tv.Tuning, tv.Set, tv.Tune{0x01, ch}}
+	}).
+		describe(tv.Tuning, "Tune to a channel",
+			stringParam("v", "channel, as 7 or 7.1"))
+	sv.handle("/raw", "POST", http.HandlerFunc(serveRaw)).
+		describe(tv.Raw, "Send a command in the TV's own protocol and return its reply",
+			stringParam("v", "command"))
 	return sv
 }
 
+// handlers lists everything the web server serves, by pattern. Apart from
+// the remote control page at /, each is described in /openapi.json.
+func handlers(sv *tvServer) map[string]http.Handler {
+	return map[string]http.Handler{
+		"/tv/":          sv,
+		"/tvs":          http.HandlerFunc(serveTVList),
+		"/openapi.json": http.HandlerFunc(sv.serveOpenAPI),
+		"/api/v1/":      &apiServer{},
+		"/events":       http.HandlerFunc(serveEvents),
+		"/scenes":       http.HandlerFunc(serveScenes),
+		"/scenes/":      http.HandlerFunc(serveScenes),
+		"/groups":       http.HandlerFunc(serveGroupList),
+		"/group/":       &groupServer{sv},
+		"/":             newUIHandler(),
+	}
+}
+
+// routeFor returns the registered route for a command path, if any.
+func (sv *tvServer) routeFor(path string) *route {
+	for _, rt := range sv.routes {
+		if rt.path == path {
+			return rt
+		}
+	}
+	return nil
+}
+
 func (sv *tvServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, ok := auth.authenticate(w, r, plainFailure(w)); !ok {
+		return
+	}
+
 	comp := strings.SplitN(r.URL.Path, "/", 4)
-	tvId, err := strconv.Atoi(comp[2])
-	if err != nil {
+	if len(comp) < 3 {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
-	if tvId >= len(tvs) {
-		w.WriteHeader(http.StatusBadRequest)
+	tvId, ok := findTV(comp[2])
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
 		return
 	}
-	r.URL.Path = "/" + strings.Join(comp[3:], "/")
-	sv.reqTv[r] = tvId
-	sv.mux.ServeHTTP(w, r)
-	delete(sv.reqTv, r)
+	path := "/" + strings.Join(comp[3:], "/")
+
+	var attr tv.Attribute
+	if rt := sv.routeFor(path); rt != nil {
+		attr = rt.attr
+	}
+	if !auth.authorize(w, r, tvId, attr, plainFailure(w)) {
+		return
+	}
+	sv.serveTV(w, r, tvId, path)
 }
 
-func (sv *tvServer) bindCommand(path string, o *tv.Op) {
-	sv.bindCommandGenerator(path, func(r *http.Request) *tv.Op {
-		return o
-	})
+// serveTV serves the command at path, like /power, for one TV, once the
+// request has been authorized. The TV it is for travels in the request's
+// context.
+func (sv *tvServer) serveTV(w http.ResponseWriter, r *http.Request, id int, path string) {
+	u := *r.URL
+	u.Path = path
+	r = r.WithContext(context.WithValue(r.Context(), tvContextKey, id))
+	r.URL = &u
+	sv.mux.ServeHTTP(w, r)
 }
 
-func (sv *tvServer) bindCommandGenerator(path string, generator func(*http.Request) *tv.Op) {
+// handle registers an endpoint that every TV is served at, recording it so
+// that it appears in the OpenAPI document.
+func (sv *tvServer) handle(path, method string, h http.Handler) *route {
+	rt := &route{path: path, method: method}
+	sv.routes = append(sv.routes, rt)
 	sv.mux.Handle(path, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "OPTIONS, POST")
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-		if r.Method != "POST" {
+		if r.Method != method {
+			w.Header().Set("Allow", method)
 			w.WriteHeader(http.StatusMethodNotAllowed)
 			return
 		}
+		h.ServeHTTP(w, r)
+	}))
+	return rt
+}
 
-		tvId := sv.reqTv[r]
+func (sv *tvServer) bindCommand(path string, o *tv.Op) *route {
+	return sv.bindCommandGenerator(path, func(r *http.Request) *tv.Op {
+		return o
+	})
+}
 
+func (sv *tvServer) bindCommandGenerator(path string, generator func(*http.Request) *tv.Op) *route {
+	return sv.handle(path, "POST", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		cmd := generator(r)
 		if cmd == nil {
 			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
 
-		err := tvs[tvId].Do(cmd)
+		// Refuse what the TV does not support before sending anything, and
+		// answer with the same statuses as the JSON API.
+		t := requestTV(r)
+		err := checkSupported(t, cmd)
+		if err == nil {
+			err = doOp(t, cmd)
+		}
 		//err := <-commandStream.Submit(cmd)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
+			_, status := classifyError(err)
+			w.WriteHeader(status)
 			w.Write([]byte(err.Error()))
 			return
 		}
+		hub.changed(requestTVID(r))
 		w.WriteHeader(http.StatusNoContent)
 	}))
 }
@@ -178,10 +432,22 @@ type Port struct {
 	Baud   uint   `yaml:"baud"`
 }
 
+// open opens the serial port, at 9600 baud unless another speed is given.
+func (p Port) open() (io.ReadWriteCloser, error) {
+	baud := int(p.Baud)
+	if baud == 0 {
+		baud = 9600
+	}
+	return serial.OpenPort(&serial.Config{Name: p.Device, Baud: baud})
+}
+
 type TVConfig struct {
 	V struct {
 		Name, Model string
 		Port        `yaml:",inline"`
+		// AllowRaw enables raw commands, which can do anything the TV's
+		// protocol allows.
+		AllowRaw bool `yaml:"allow_raw"`
 	}
 	ModelSpecific tv.Config
 }
@@ -198,20 +464,70 @@ func (tvc *TVConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 
 type Config struct {
 	TVs []TVConfig `yaml:"tvs"`
+	// Tokens, if any are given, are required of every request.
+	Tokens []TokenConfig          `yaml:"tokens"`
+	CORS   *CORSConfig            `yaml:"cors"`
+	MQTT   *MQTTConfig            `yaml:"mqtt"`
+	Scenes map[string][]SceneStep `yaml:"scenes"`
+	Groups map[string]GroupConfig `yaml:"groups"`
 }
 
 type Options struct {
-	Config      string `short:"c" long:"config" description:"configuration file location" default:"./config.yml"`
-	BindAddress string `short:"a" long:"addr" description:"bind address (web server)" default:":5456"`
+	Config       string        `short:"c" long:"config" description:"configuration file location" default:"./config.yml"`
+	BindAddress  string        `short:"a" long:"addr" description:"bind address (web server)" default:":5456"`
+	GRPCAddress  string        `long:"grpc-addr" description:"bind address (gRPC API), e.g. :5457; off unless given"`
+	TLSCert      string        `long:"tls-cert" description:"serve HTTPS with this certificate (reloaded when it changes)"`
+	TLSKey       string        `long:"tls-key" description:"private key for --tls-cert"`
+	TLSClientCA  string        `long:"tls-client-ca" description:"require client certificates signed by a CA in this bundle"`
+	PollInterval time.Duration `long:"poll-interval" description:"how often to ask each TV for its state" default:"5s"`
+
+	Ctl  ctlCommand  `command:"ctl" description:"Drive TVs through a running server"`
+	Exec execCommand `command:"exec" description:"Drive one TV directly, without a server or configuration"`
 }
 
 var tvs []tv.TV
+var tvConfigs []TVConfig
+
+// tvNames maps each configured name to the TV's index in tvs.
+var tvNames = map[string]int{}
+
+// findTV resolves a TV by its configured name or, failing that, by its
+// position in the configuration.
+func findTV(id string) (int, bool) {
+	if i, ok := tvNames[id]; ok {
+		return i, true
+	}
+	i, err := strconv.Atoi(id)
+	if err != nil || i < 0 || i >= len(tvs) {
+		return 0, false
+	}
+	return i, true
+}
+
+// tvName is how a TV is addressed in URLs and topics: by its name, or by
+// its position if it has none.
+func tvName(id int) string {
+	if name := tvConfigs[id].V.Name; name != "" {
+		return name
+	}
+	return strconv.Itoa(id)
+}
 
 func main() {
 	var opts Options
-	if _, err := flags.Parse(&opts); err != nil {
+	parser := flags.NewParser(&opts, flags.Default)
+	parser.SubcommandsOptional = true
+	if _, err := parser.Parse(); err != nil {
+		var exit *exitError
+		if errors.As(err, &exit) {
+			os.Exit(exit.status)
+		}
 		os.Exit(1)
 	}
+	// Subcommands have run by now; only the server is left.
+	if parser.Active != nil {
+		return
+	}
 
 	cfgb, err := ioutil.ReadFile(opts.Config)
 	if err != nil {
@@ -224,24 +540,45 @@ func main() {
 		panic(err)
 	}
 
+	ports := map[string]io.ReadWriteCloser{}
 	for _, tvc := range cfg.TVs {
-		/*
-			serialPort, err := sers.Open(tvc.V.Device)
+		// TVs on the same port, like a daisy chain of LGs, share it.
+		port, ok := ports[tvc.V.Device]
+		if !ok && tvc.V.Device != "" {
+			port, err = tvc.V.Port.open()
 			if err != nil {
 				log.Fatalf("failed to open serial device `%v`: %v\n", tvc.V.Device, err.Error())
 			}
-
-			err = serialPort.SetMode(int(tvc.V.Baud), 8, sers.N, 1, sers.NO_HANDSHAKE)
-			if err != nil {
-				log.Fatalf("failed to configure serial device: %v\n", err.Error())
-			}
-
-		*/
-		newTv, err := tv.New(tvc.V.Model, nil, tvc.ModelSpecific)
+			ports[tvc.V.Device] = port
+		}
+		newTv, err := tv.New(tvc.V.Model, port, tvc.ModelSpecific)
 		if err != nil {
 			log.Fatalf("failed to instantiate TV: %v\n", err.Error())
 		}
+		if tvc.V.Name != "" {
+			if _, ok := tvNames[tvc.V.Name]; ok {
+				log.Fatalf("more than one TV is named `%v`\n", tvc.V.Name)
+			}
+			tvNames[tvc.V.Name] = len(tvs)
+		}
 		tvs = append(tvs, newTv)
+		tvConfigs = append(tvConfigs, tvc)
+	}
+
+	if len(cfg.Tokens) > 0 {
+		auth, err = newAuthorizer(cfg.Tokens)
+		if err != nil {
+			log.Fatalf("failed to configure tokens: %v\n", err.Error())
+		}
+	}
+
+	scenes, err = newScenes(cfg.Scenes)
+	if err != nil {
+		log.Fatalf("failed to configure scenes: %v\n", err.Error())
+	}
+	groups, err = newGroups(cfg.Groups)
+	if err != nil {
+		log.Fatalf("failed to configure groups: %v\n", err.Error())
 	}
 
 	quitC := make(chan struct{})
@@ -251,92 +588,67 @@ func main() {
 	signal.Notify(sigChan, os.Interrupt, os.Kill)
 
 	sv := newTVServer()
-	sv.bindCommand("/mute", &tv.Op{tv.Mute, tv.Set, true})
-	sv.bindCommand("/unmute", &tv.Op{tv.Mute, tv.Set, false})
-	sv.bindCommandGenerator("/power", boolGenerator("v", tv.Power))
-	sv.bindCommandGenerator("/screen", boolGenerator("v", tv.Screen))
-	sv.bindCommandGenerator("/osd", boolGenerator("v", tv.OSD))
-	sv.bindCommandGenerator("/volume", func(r *http.Request) *tv.Op {
-		dir := r.FormValue("d")
-		formV := r.FormValue("v")
-		if formV == "max" {
-			return &tv.Op{tv.Volume, tv.Set, 100}
-		} else if formV == "min" {
-			return &tv.Op{tv.Volume, tv.Set, 0}
-		}
-
-		val, e := strconv.Atoi(formV)
-		if e != nil {
-			return nil
-		}
-		if dir == "up" {
-			return &tv.Op{tv.Volume, tv.Increment, 1}
-		} else if dir == "down" {
-			return &tv.Op{tv.Volume, tv.Decrement, 1}
-		} else {
-			return &tv.Op{tv.Volume, tv.Set, val}
-		}
-	})
-	sv.bindCommandGenerator("/input", func(r *http.Request) *tv.Op {
-		connectionName := r.FormValue("c")
-		connectionNumberS := r.FormValue("n")
-		if connectionName == "" || connectionNumberS == "" {
-			return nil
-		}
-		connectionNumber, err := strconv.Atoi(connectionNumberS)
-		if err != nil {
-			return nil
-		}
 
-		connection, ok := inputNameToTV[connectionName]
-		if !ok {
-			return nil
-		}
-		return &tv.Op{tv.Input, tv.Set, tv.InputNumber{connection, connectionNumber}}
+	//commandStream.Run()
 
-	})
-	sv.bindCommandGenerator("/contrast", intGenerator("v", tv.Contrast))
-	sv.bindCommandGenerator("/brightness", intGenerator("v", tv.Brightness))
-	sv.bindCommandGenerator("/color", intGenerator("v", tv.Color))
-	sv.bindCommandGenerator("/tint", intGenerator("v", tv.Tint))
-	sv.bindCommandGenerator("/sharpness", intGenerator("v", tv.Sharpness))
-	sv.bindCommandGenerator("/balance", intGenerator("v", tv.AudioBalance))
-	sv.bindCommandGenerator("/color_temperature", intGenerator("v", tv.ColorTemperature))
-	sv.bindCommandGenerator("/backlight", intGenerator("v", tv.Backlight))
-	sv.bindCommandGenerator("/channel", func(r *http.Request) *tv.Op {
-		ch, err := ParseChannel(r.FormValue("v"))
-		if err != nil {
-			return nil
-		}
-		/*
-			antenna := r.FormValue("a")
-			if antenna == "" {
-				return nil
-			}
-		*/
-		return &tv.Op{tv.Tuning, tv.Set, tv.Tune{0x01, ch}}
-	})
-	sv.bindCommandGenerator("/raw", func(r *http.Request) *tv.Op {
-		cmd := r.FormValue("v")
-		if cmd == "" {
-			return nil
-		}
-		return &tv.Op{tv.Raw, tv.Set, []byte(cmd)}
-	})
+	for pattern, h := range handlers(sv) {
+		http.Handle(pattern, h)
+	}
 
-	//commandStream.Run()
+	stopHub := make(chan struct{})
+	defer close(stopHub)
+	hub = newStateHub(opts.PollInterval)
+	hub.start(stopHub)
 
-	http.Handle("/tv/", sv)
+	if cfg.MQTT != nil {
+		bridge := newMQTTBridge(*cfg.MQTT)
+		bridge.start(hub)
+		defer bridge.stop()
+	}
 
 	go func() {
 		<-sigChan
 		quitC <- struct{}{}
 	}()
 
+	cors := defaultCORS
+	if cfg.CORS != nil {
+		cors = *cfg.CORS
+	}
+
+	server := &http.Server{Addr: opts.BindAddress, Handler: withCORS(cors, http.DefaultServeMux)}
+	if opts.TLSCert != "" || opts.TLSKey != "" || opts.TLSClientCA != "" {
+		if opts.TLSCert == "" || opts.TLSKey == "" {
+			log.Fatalf("--tls-cert and --tls-key must be given together\n")
+		}
+		certs, err := newCertReloader(opts.TLSCert, opts.TLSKey, opts.TLSClientCA)
+		if err != nil {
+			log.Fatalf("failed to load TLS certificate: %v\n", err.Error())
+		}
+		server.TLSConfig = certs.TLSConfig()
+	}
+
 	go func() {
-		http.ListenAndServe(opts.BindAddress, nil)
+		var err error
+		if server.TLSConfig != nil {
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			err = server.ListenAndServe()
+		}
+		log.Fatalf("failed to serve: %v\n", err.Error())
 	}()
 
+	if opts.GRPCAddress != "" {
+		lis, err := net.Listen("tcp", opts.GRPCAddress)
+		if err != nil {
+			log.Fatalf("failed to listen for gRPC: %v\n", err.Error())
+		}
+		go func() {
+			err := newGRPCServer(server.TLSConfig).Serve(lis)
+			log.Fatalf("failed to serve gRPC: %v\n", err.Error())
+		}()
+	}
+
 	<-quitC
 	//serialPort.Close()
 }
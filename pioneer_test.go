@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/DHowett/avantgarde/tv"
+)
+
+// TestConcurrentRequests hammers the form endpoints from many goroutines
+// at once; run it with -race to check that requests do not share state.
+func TestConcurrentRequests(t *testing.T) {
+	const nTVs, nRequests = 4, 400
+
+	var fakes []tv.TV
+	for i := 0; i < nTVs; i++ {
+		fake, err := tv.New("fake", nil, tv.NewConfig("fake"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		fakes = append(fakes, fake)
+	}
+	defer useFakeTVs(fakes...)()
+
+	srv := httptest.NewServer(newTVServer())
+	defer srv.Close()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, nRequests)
+	for i := 0; i < nRequests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Alternate between names and numeric aliases.
+			id := fmt.Sprint(i % nTVs)
+			if i%2 == 0 {
+				id = fmt.Sprintf("fake%d", i%nTVs)
+			}
+			resp, err := http.PostForm(srv.URL+"/tv/"+id+"/volume", url.Values{"v": {fmt.Sprint(i % 100)}})
+			if err != nil {
+				errs <- err
+				return
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusNoContent {
+				errs <- fmt.Errorf("request %d: %s", i, resp.Status)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	for i, fake := range fakes {
+		f := fake.(*fakeTV)
+		f.mu.Lock()
+		n := len(f.ops)
+		for _, op := range f.ops {
+			if op.Attribute != tv.Volume || op.Value.(int)%nTVs != i {
+				t.Errorf("TV %d got %v, which was meant for another TV!", i, *op)
+			}
+		}
+		f.mu.Unlock()
+		if n != nRequests/nTVs {
+			t.Errorf("TV %d got %d requests instead of %d!", i, n, nRequests/nTVs)
+		}
+	}
+}
+
+func TestCommandErrors(t *testing.T) {
+	fake := &fakeTV{}
+	defer useFakeTVs(fake)()
+	sv := newTVServer()
+
+	post := func(path string, form url.Values) int {
+		r := httptest.NewRequest("POST", "/tv/fake0"+path, nil)
+		r.PostForm = form
+		w := httptest.NewRecorder()
+		sv.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	// The fake TV does not support the screen, so it is never asked to.
+	if code := post("/screen", url.Values{"v": {"1"}}); code != http.StatusNotImplemented || len(fake.ops) != 0 {
+		t.Errorf("Got %d and %v instead of %d and no ops for an unsupported command!", code, fake.ops, http.StatusNotImplemented)
+	}
+
+	for _, test := range []struct {
+		err    error
+		status int
+	}{
+		{fmt.Errorf("fake: %w", tv.ErrInvalidValue), http.StatusBadRequest},
+		{fmt.Errorf("fake: %w", tv.ErrTimeout), http.StatusGatewayTimeout},
+		{fmt.Errorf("fake: %w", tv.ErrDisconnected), http.StatusServiceUnavailable},
+		{fmt.Errorf("fake: broken"), http.StatusInternalServerError},
+	} {
+		fake.err = test.err
+		if code := post("/volume", url.Values{"v": {"15"}}); code != test.status {
+			t.Errorf("Got %d instead of %d for %v!", code, test.status, test.err)
+		}
+	}
+}
+
+func TestRouteErrors(t *testing.T) {
+	fake := &fakeSwitcher{}
+	defer useFakeTVs(fake)()
+	sv := newTVServer()
+
+	for _, test := range []struct {
+		err    error
+		status int
+	}{
+		{nil, http.StatusNoContent},
+		{fmt.Errorf("fake: no such input: %w", tv.ErrInvalidValue), http.StatusBadRequest},
+		{fmt.Errorf("fake: %w", tv.ErrTimeout), http.StatusGatewayTimeout},
+		{fmt.Errorf("fake: broken"), http.StatusInternalServerError},
+	} {
+		fake.err = test.err
+		r := httptest.NewRequest("POST", "/tv/fake0/route", nil)
+		r.PostForm = url.Values{"i": {"9"}, "o": {"1"}}
+		w := httptest.NewRecorder()
+		sv.ServeHTTP(w, r)
+		if w.Code != test.status {
+			t.Errorf("Got %d instead of %d for %v!", w.Code, test.status, test.err)
+		}
+		if test.err != nil && w.Body.String() != test.err.Error() {
+			t.Errorf("Got %q instead of the error message for %v!", w.Body.String(), test.err)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/DHowett/avantgarde/tv"
+)
+
+// maxRawCommand bounds raw commands before they reach a driver; no TV's
+// protocol needs anywhere near this many bytes in one command.
+const maxRawCommand = 512
+
+// errRawDisabled is returned for raw commands to a TV whose configuration
+// does not set allow_raw.
+var errRawDisabled = errors.New("raw commands are disabled for this tv")
+
+func rawAllowed(id int) bool {
+	return tvConfigs[id].V.AllowRaw
+}
+
+// sendRaw sends a raw command to a TV, logging who sent it, and returns the
+// TV's reply if its driver can report one.
+func sendRaw(r *http.Request, id int, cmd []byte) ([]byte, error) {
+	var g *grant
+	if auth != nil {
+		g = auth.grantFor(r)
+	}
+	return doRaw(id, cmd, r.RemoteAddr, g)
+}
+
+// doRaw sends a raw command on behalf of a client, given by its address and
+// grant for the log.
+func doRaw(id int, cmd []byte, from string, g *grant) ([]byte, error) {
+	if !rawAllowed(id) {
+		return nil, errRawDisabled
+	}
+	if len(cmd) == 0 || len(cmd) > maxRawCommand {
+		return nil, fmt.Errorf("raw commands must be 1 to %d bytes: %w", maxRawCommand, tv.ErrInvalidValue)
+	}
+
+	who := "no token"
+	if g != nil {
+		who = "token `" + g.name + "`"
+	}
+	log.Printf("raw: tv %d from %s with %s: %q\n", id, from, who, cmd)
+
+	t := tvs[id]
+	if rr, ok := t.(tv.RawReplier); ok {
+		return rr.DoRaw(cmd)
+	}
+	return nil, t.Do(&tv.Op{tv.Raw, tv.Set, cmd})
+}
+
+// serveRaw is the form endpoint for raw commands. It answers with the TV's
+// reply, or with no content when the TV gives none.
+func serveRaw(w http.ResponseWriter, r *http.Request) {
+	cmd := r.FormValue("v")
+	if cmd == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	reply, err := sendRaw(r, requestTVID(r), []byte(cmd))
+	switch {
+	case errors.Is(err, errRawDisabled):
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(err.Error()))
+		return
+	case errors.Is(err, tv.ErrInvalidValue):
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	case err != nil:
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	hub.changed(requestTVID(r))
+	if len(reply) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Write(reply)
+}
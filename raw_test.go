@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/DHowett/avantgarde/tv"
+)
+
+func postRaw(sv *tvServer, path, v string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", path, strings.NewReader(url.Values{"v": {v}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	sv.ServeHTTP(w, req)
+	return w
+}
+
+func TestRawDisabled(t *testing.T) {
+	fake := &fakeTV{}
+	defer useFakeTVs(fake)()
+
+	sv := newTVServer()
+	if w := postRaw(sv, "/tv/fake0/raw", "POWR1   "); w.Code != http.StatusForbidden {
+		t.Errorf("Got %d instead of %d for a raw command to a locked TV!", w.Code, http.StatusForbidden)
+	}
+
+	w := httptest.NewRecorder()
+	(&apiServer{}).ServeHTTP(w, httptest.NewRequest("POST", "/api/v1/tv/0/op", strings.NewReader(`{"attribute":"raw","value":"POWR1   "}`)))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Got %d instead of %d for a raw API op to a locked TV!", w.Code, http.StatusForbidden)
+	}
+	if len(fake.ops) != 0 {
+		t.Errorf("A locked TV was sent %v!", fake.ops)
+	}
+
+	if doc := sv.openAPI(); doc.Paths["/tv/fake0/raw"] != nil {
+		t.Errorf("/raw is documented for a locked TV!")
+	}
+	for _, c := range newAPITV(0).Capabilities {
+		if c.Attribute == "raw" {
+			t.Errorf("raw is listed among a locked TV's capabilities!")
+		}
+	}
+}
+
+func TestRawReply(t *testing.T) {
+	fake := &fakeTV{reply: []byte("OK")}
+	defer useFakeTVs(fake, struct{ tv.TV }{&fakeTV{}})()
+	tvConfigs[0].V.AllowRaw = true
+	tvConfigs[1].V.AllowRaw = true
+
+	sv := newTVServer()
+	w := postRaw(sv, "/tv/fake0/raw", "POWR1   ")
+	if w.Code != http.StatusOK || w.Body.String() != "OK" {
+		t.Errorf("Got %d %q instead of the TV's reply!", w.Code, w.Body.String())
+	}
+	if len(fake.ops) != 1 || string(fake.ops[0].Value.([]byte)) != "POWR1   " {
+		t.Errorf("Got %v instead of the raw command!", fake.ops)
+	}
+
+	// TVs that cannot pass on their replies still take raw commands.
+	if w := postRaw(sv, "/tv/fake1/raw", "POWR1   "); w.Code != http.StatusNoContent {
+		t.Errorf("Got %d instead of %d for a TV without replies!", w.Code, http.StatusNoContent)
+	}
+
+	if w := postRaw(sv, "/tv/fake0/raw", strings.Repeat("x", maxRawCommand+1)); w.Code != http.StatusBadRequest {
+		t.Errorf("Got %d instead of %d for an overlong raw command!", w.Code, http.StatusBadRequest)
+	}
+
+	w = httptest.NewRecorder()
+	(&apiServer{}).ServeHTTP(w, httptest.NewRequest("POST", "/api/v1/tv/0/op", strings.NewReader(`{"attribute":"raw","value":"POWR1   "}`)))
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != `{"reply":"OK"}` {
+		t.Errorf("Got %d %s instead of the TV's reply!", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,4 @@
+// Package rpc is the gRPC API to avantgarde, generated from avantgarde.proto.
+package rpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative avantgarde.proto
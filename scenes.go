@@ -0,0 +1,464 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DHowett/avantgarde/tv"
+)
+
+// SceneStep is one step of a scene in config.yml. A step performs an
+// operation on some TVs, waits, or holds further steps to run one after
+// another or all at once:
+//
+//	scenes:
+//	  open-bar:
+//	    - tvs: [bar-1, bar-2]
+//	      do: power on
+//	      if: power off
+//	    - wait: 20s
+//	    - parallel:
+//	        - {tvs: [bar-1, bar-2], do: input hdmi 2}
+//	        - {tvs: [kitchen], do: screen off, on_error: continue}
+type SceneStep struct {
+	// TVs are sent the step's operation concurrently.
+	TVs []string `yaml:"tvs"`
+	// Do is an operation written as for ctl, like "volume 10".
+	Do string
+	// If limits the operation to the TVs whose state matches, like "power
+	// off" or "input hdmi 1".
+	If       string `yaml:"if"`
+	Wait     time.Duration
+	Steps    []SceneStep
+	Parallel []SceneStep
+	// OnError is "stop", the default, to abandon the rest of the scene if
+	// the step fails, or "continue" to carry on regardless.
+	OnError string `yaml:"on_error"`
+}
+
+// Statuses of the steps in a scene's report.
+const (
+	stepDone    = "done"
+	stepSkipped = "skipped"
+	stepFailed  = "failed"
+	stepNotRun  = "not_run"
+)
+
+type sceneStep struct {
+	// label numbers the step from 1, and nested steps after a dot, e.g. 3.2.
+	label  string
+	action string
+	tvs    []int
+	op     *tv.Op
+	cond   *tv.Op
+	wait   time.Duration
+	// steps run in order, unless parallel is set.
+	steps     []*sceneStep
+	parallel  bool
+	keepGoing bool
+}
+
+type scene struct {
+	name  string
+	steps []*sceneStep
+}
+
+// scenes holds the configured scenes by name.
+var scenes = map[string]*scene{}
+
+// sceneResult reports how one step went, on one TV for steps that have
+// an operation.
+type sceneResult struct {
+	Step   string    `json:"step"`
+	Action string    `json:"action"`
+	TV     string    `json:"tv,omitempty"`
+	Status string    `json:"status"`
+	Error  *apiError `json:"error,omitempty"`
+}
+
+// sceneReport is the response to running a scene. Its status is "failed"
+// if any step failed, even one that let the scene continue.
+type sceneReport struct {
+	Scene   string        `json:"scene"`
+	Status  string        `json:"status"`
+	Results []sceneResult `json:"results"`
+}
+
+// parseCommand reads an operation written as for ctl, like "input hdmi 2".
+func parseCommand(s string) (*tv.Op, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return nil, errors.New("empty command")
+	}
+	a, err := ctlOp(fields[0], fields[1:])
+	if err != nil {
+		return nil, err
+	}
+	return a.toOp()
+}
+
+// stateMatches reports whether a TV's state satisfies a condition, which
+// is a set operation on one of the attributes that State reports.
+func stateMatches(state *tv.State, cond *tv.Op) bool {
+	switch cond.Attribute {
+	case tv.Power:
+		return state.Power == cond.Value.(bool)
+	case tv.Mute:
+		return state.Mute == cond.Value.(bool)
+	case tv.Screen:
+		return state.Screen == cond.Value.(bool)
+	case tv.Volume:
+		return state.Volume == cond.Value.(int)
+	case tv.Input:
+		return state.Input == cond.Value.(tv.InputNumber)
+	}
+	return false
+}
+
+func newSceneSteps(prefix string, cfgs []SceneStep) ([]*sceneStep, error) {
+	var steps []*sceneStep
+	for i, c := range cfgs {
+		s := &sceneStep{label: prefix + strconv.Itoa(i+1)}
+		fail := func(format string, args ...interface{}) error {
+			return fmt.Errorf("step %s: %s", s.label, fmt.Sprintf(format, args...))
+		}
+
+		kinds := 0
+		for _, set := range []bool{c.Do != "", c.Wait != 0, len(c.Steps) > 0, len(c.Parallel) > 0} {
+			if set {
+				kinds++
+			}
+		}
+		if kinds != 1 {
+			return nil, fail("needs exactly one of do, wait, steps and parallel")
+		}
+		if c.Do == "" && (len(c.TVs) > 0 || c.If != "") {
+			return nil, fail("tvs and if go with do")
+		}
+		switch c.OnError {
+		case "", "stop":
+		case "continue":
+			s.keepGoing = true
+		default:
+			return nil, fail("on_error is %q, not stop or continue", c.OnError)
+		}
+
+		var err error
+		switch {
+		case c.Do != "":
+			s.action = c.Do
+			if s.op, err = parseCommand(c.Do); err != nil {
+				return nil, fail("%v", err)
+			}
+			if len(c.TVs) == 0 {
+				return nil, fail("no tvs to %s", c.Do)
+			}
+			for _, name := range c.TVs {
+				id, ok := findTV(name)
+				if !ok {
+					return nil, fail("no such tv `%s`", name)
+				}
+				if s.op.Attribute == tv.Raw && !rawAllowed(id) {
+					return nil, fail("%v: %s", errRawDisabled, name)
+				}
+				if s.op.Attribute != tv.Raw {
+					if err := checkSupported(tvs[id], s.op); err != nil {
+						return nil, fail("%v: %s", err, name)
+					}
+				}
+				s.tvs = append(s.tvs, id)
+			}
+			if c.If != "" {
+				if s.cond, err = parseCommand(c.If); err != nil {
+					return nil, fail("if: %v", err)
+				}
+				switch s.cond.Attribute {
+				case tv.Power, tv.Mute, tv.Screen, tv.Volume, tv.Input:
+				default:
+					return nil, fail("if: cannot test %s", s.cond.Attribute)
+				}
+				if s.cond.Operator != tv.Set {
+					return nil, fail("if: %q is not a value", c.If)
+				}
+			}
+		case c.Wait != 0:
+			if c.Wait < 0 {
+				return nil, fail("cannot wait %v", c.Wait)
+			}
+			s.action, s.wait = "wait "+c.Wait.String(), c.Wait
+		case len(c.Steps) > 0:
+			s.action = "steps"
+			s.steps, err = newSceneSteps(s.label+".", c.Steps)
+		default:
+			s.action, s.parallel = "parallel", true
+			s.steps, err = newSceneSteps(s.label+".", c.Parallel)
+		}
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, s)
+	}
+	return steps, nil
+}
+
+func newScene(name string, cfgs []SceneStep) (*scene, error) {
+	if len(cfgs) == 0 {
+		return nil, fmt.Errorf("scene `%s` has no steps", name)
+	}
+	steps, err := newSceneSteps("", cfgs)
+	if err != nil {
+		return nil, fmt.Errorf("scene `%s`: %v", name, err)
+	}
+	return &scene{name, steps}, nil
+}
+
+func newScenes(cfgs map[string][]SceneStep) (map[string]*scene, error) {
+	m := make(map[string]*scene)
+	for name, steps := range cfgs {
+		s, err := newScene(name, steps)
+		if err != nil {
+			return nil, err
+		}
+		m[name] = s
+	}
+	return m, nil
+}
+
+// allowedBy reports whether a grant covers everything the scene does, and
+// every state its conditions read.
+func (sc *scene) allowedBy(g *grant) bool {
+	var allowed func(steps []*sceneStep) bool
+	allowed = func(steps []*sceneStep) bool {
+		for _, s := range steps {
+			for _, id := range s.tvs {
+				if !g.allows(id, s.op.Attribute, true) || s.cond != nil && !g.allows(id, s.cond.Attribute, false) {
+					return false
+				}
+			}
+			if !allowed(s.steps) {
+				return false
+			}
+		}
+		return true
+	}
+	return allowed(sc.steps)
+}
+
+// sceneRun performs a scene on behalf of a client, given by its address
+// and grant for the log of raw commands. Once ctx is done, as when the
+// client goes away, the steps not yet begun are not run.
+type sceneRun struct {
+	ctx  context.Context
+	from string
+	g    *grant
+}
+
+func (sc *scene) run(ctx context.Context, from string, g *grant) *sceneReport {
+	run := &sceneRun{ctx, from, g}
+	report := &sceneReport{Scene: sc.name, Status: stepDone}
+	report.Results, _ = run.sequence(sc.steps)
+	if err := ctx.Err(); err != nil {
+		log.Printf("scene %s for %s stopped early: %v", sc.name, from, err)
+	}
+	for _, r := range report.Results {
+		if r.Status == stepFailed {
+			report.Status = stepFailed
+		}
+	}
+	return report
+}
+
+// sequence performs steps one after another. It reports false if one of
+// them failed and stopped the rest, which are reported as not run.
+func (run *sceneRun) sequence(steps []*sceneStep) ([]sceneResult, bool) {
+	var results []sceneResult
+	for i, s := range steps {
+		if run.ctx.Err() != nil {
+			for _, rest := range steps[i:] {
+				results = append(results, notRun(rest)...)
+			}
+			return results, false
+		}
+		r, ok := run.step(s)
+		results = append(results, r...)
+		if !ok {
+			for _, rest := range steps[i+1:] {
+				results = append(results, notRun(rest)...)
+			}
+			return results, false
+		}
+	}
+	return results, true
+}
+
+// step performs one step and reports whether the scene may go on.
+func (run *sceneRun) step(s *sceneStep) ([]sceneResult, bool) {
+	var results []sceneResult
+	ok := true
+	switch {
+	case s.op != nil:
+		results = make([]sceneResult, len(s.tvs))
+		var wg sync.WaitGroup
+		for i, id := range s.tvs {
+			wg.Add(1)
+			go func(i, id int) {
+				defer wg.Done()
+				defer func() {
+					if r := recover(); r != nil {
+						results[i] = panicked(s, tvName(id), r)
+					}
+				}()
+				results[i] = run.do(s, id)
+			}(i, id)
+		}
+		wg.Wait()
+		for _, r := range results {
+			ok = ok && r.Status != stepFailed
+		}
+	case s.wait > 0:
+		timer := time.NewTimer(s.wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			results = []sceneResult{{Step: s.label, Action: s.action, Status: stepDone}}
+		case <-run.ctx.Done():
+			return notRun(s), false
+		}
+	case s.parallel:
+		parts := make([][]sceneResult, len(s.steps))
+		oks := make([]bool, len(s.steps))
+		var wg sync.WaitGroup
+		for i, child := range s.steps {
+			wg.Add(1)
+			go func(i int, child *sceneStep) {
+				defer wg.Done()
+				defer func() {
+					if r := recover(); r != nil {
+						parts[i], oks[i] = []sceneResult{panicked(child, "", r)}, false
+					}
+				}()
+				parts[i], oks[i] = run.step(child)
+			}(i, child)
+		}
+		wg.Wait()
+		for i := range parts {
+			results = append(results, parts[i]...)
+			ok = ok && oks[i]
+		}
+	default:
+		results, ok = run.sequence(s.steps)
+	}
+	return results, ok || s.keepGoing
+}
+
+// do performs a step's operation on one TV, if its condition holds.
+func (run *sceneRun) do(s *sceneStep, id int) sceneResult {
+	r := sceneResult{Step: s.label, Action: s.action, TV: tvName(id), Status: stepDone}
+	failed := func(err error) sceneResult {
+		code, _ := classifyError(err)
+		r.Status, r.Error = stepFailed, &apiError{code, err.Error()}
+		return r
+	}
+
+	if s.cond != nil {
+		state, err := tvs[id].State()
+		if err != nil {
+			return failed(err)
+		}
+		if !stateMatches(state, s.cond) {
+			r.Status = stepSkipped
+			return r
+		}
+	}
+
+	var err error
+	if s.op.Attribute == tv.Raw {
+		_, err = doRaw(id, s.op.Value.([]byte), run.from, run.g)
+	} else {
+		err = doOp(tvs[id], s.op)
+	}
+	if err != nil {
+		return failed(err)
+	}
+	hub.changed(id)
+	return r
+}
+
+// panicked reports a step that panicked on a TV, or on none, as failed.
+// Steps run on goroutines of their own, where a driver's panic would
+// otherwise take the whole server down.
+func panicked(s *sceneStep, tvName string, r interface{}) sceneResult {
+	log.Printf("scene step %s %s: panic: %v\n", s.label, tvName, r)
+	return sceneResult{Step: s.label, Action: s.action, TV: tvName, Status: stepFailed, Error: &apiError{codeInternal, fmt.Sprint("panic: ", r)}}
+}
+
+// notRun reports a step, and any steps within it, as not run.
+func notRun(s *sceneStep) []sceneResult {
+	if s.op != nil {
+		var results []sceneResult
+		for _, id := range s.tvs {
+			results = append(results, sceneResult{Step: s.label, Action: s.action, TV: tvName(id), Status: stepNotRun})
+		}
+		return results
+	}
+	if s.steps == nil {
+		return []sceneResult{{Step: s.label, Action: s.action, Status: stepNotRun}}
+	}
+	var results []sceneResult
+	for _, child := range s.steps {
+		results = append(results, notRun(child)...)
+	}
+	return results
+}
+
+// serveScenes lists the scenes a token may run at /scenes, and runs one
+// at /scenes/{name}. The response waits for the whole scene, waits and
+// all, and reports every step. A client that goes away stops the scene
+// before its next step.
+func serveScenes(w http.ResponseWriter, r *http.Request) {
+	g, ok := auth.authenticate(w, r, apiFailure(w))
+	if !ok {
+		return
+	}
+
+	name := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/scenes"), "/")
+	if name == "" {
+		if r.Method != "GET" {
+			writeAPIError(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "use GET")
+			return
+		}
+		names := []string{}
+		for name, sc := range scenes {
+			if g == nil || sc.allowedBy(g) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		writeJSON(w, http.StatusOK, names)
+		return
+	}
+
+	if r.Method != "POST" {
+		writeAPIError(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "use POST")
+		return
+	}
+	sc, ok := scenes[name]
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, codeNotFound, fmt.Sprintf("no such scene %q", name))
+		return
+	}
+	if g != nil && !sc.allowedBy(g) {
+		logUnauthorized(r, g, "not allowed scene "+name)
+		apiFailure(w)(http.StatusForbidden, "this token may not run scene "+name)
+		return
+	}
+	writeJSON(w, http.StatusOK, sc.run(r.Context(), r.RemoteAddr, g))
+}
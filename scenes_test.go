@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/DHowett/avantgarde/tv"
+)
+
+// useTestScenes configures scenes from YAML for a test and returns a
+// function that removes them again.
+func useTestScenes(t *testing.T, config string) func() {
+	var cfgs map[string][]SceneStep
+	if err := yaml.Unmarshal([]byte(config), &cfgs); err != nil {
+		t.Fatalf("Failed to parse scenes: %v", err)
+	}
+	s, err := newScenes(cfgs)
+	if err != nil {
+		t.Fatalf("Failed to configure scenes: %v", err)
+	}
+	scenes = s
+	return func() { scenes = map[string]*scene{} }
+}
+
+func statuses(report *sceneReport) string {
+	var s []string
+	for _, r := range report.Results {
+		s = append(s, fmt.Sprintf("%s %s %s", r.Step, r.TV, r.Status))
+	}
+	return strings.Join(s, ", ")
+}
+
+func TestNewScene(t *testing.T) {
+	defer useFakeTVs(&fakeTV{}, &fakeTV{})()
+
+	for _, bad := range []string{
+		`[]`,
+		`[{tvs: [nope], do: power on}]`,
+		`[{tvs: [fake0], do: warp 9}]`,
+		`[{do: power on}]`,
+		`[{tvs: [fake0], do: power on, wait: 1s}]`,
+		`[{wait: 1s, tvs: [fake0]}]`,
+		`[{tvs: [fake0], do: power on, on_error: retry}]`,
+		`[{tvs: [fake0], do: power on, if: brightness 10}]`,
+		`[{tvs: [fake0], do: power on, if: volume +3}]`,
+		`[{tvs: [fake0], do: raw ka 01 01}]`,
+		`[{parallel: [{tvs: [fake0], do: volume loud}]}]`,
+		`[{tvs: [fake0], do: power toggle}]`,
+		`[{tvs: [fake0], do: screen off}]`,
+	} {
+		var cfgs []SceneStep
+		if err := yaml.Unmarshal([]byte(bad), &cfgs); err != nil {
+			t.Fatalf("Failed to parse %s: %v", bad, err)
+		}
+		if _, err := newScene("bad", cfgs); err == nil {
+			t.Errorf("Accepted %s!", bad)
+		}
+	}
+}
+
+func TestSceneRun(t *testing.T) {
+	fakes := []*fakeTV{{}, {}}
+	defer useFakeTVs(fakes[0], fakes[1])()
+	defer useTestScenes(t, `
+open:
+  - tvs: [fake0, fake1]
+    do: power on
+  - wait: 1ms
+  - parallel:
+      - {tvs: [fake0], do: input hdmi 2}
+      - steps:
+          - {tvs: [fake1], do: volume 10}
+          - {tvs: [fake1], do: mute}
+  - tvs: [fake0, fake1]
+    do: power on
+    if: power off
+`)()
+
+	report := scenes["open"].run(context.Background(), "test", nil)
+	if report.Status != stepDone {
+		t.Errorf("Got %s instead of done: %+v", report.Status, report.Results)
+	}
+	expect := "1 fake0 done, 1 fake1 done, 2  done, 3.1 fake0 done, 3.2.1 fake1 done, 3.2.2 fake1 done, 4 fake0 skipped, 4 fake1 skipped"
+	if got := statuses(report); got != expect {
+		t.Errorf("Got %s instead of %s!", got, expect)
+	}
+
+	ops := fmt.Sprint(*fakes[0].ops[0], *fakes[0].ops[1], *fakes[1].ops[1], *fakes[1].ops[2])
+	if expect := fmt.Sprint(tv.Op{tv.Power, tv.Set, true}, tv.Op{tv.Input, tv.Set, tv.InputNumber{tv.HDMI, 2}}, tv.Op{tv.Volume, tv.Set, 10}, tv.Op{tv.Mute, tv.Set, true}); ops != expect {
+		t.Errorf("Got %s instead of %s!", ops, expect)
+	}
+	if len(fakes[0].ops) != 2 || len(fakes[1].ops) != 3 {
+		t.Errorf("Got %v and %v, with operations that should have been skipped!", fakes[0].ops, fakes[1].ops)
+	}
+}
+
+func TestSceneErrorPolicy(t *testing.T) {
+	fakes := []*fakeTV{{}, {err: fmt.Errorf("fake: %w", tv.ErrTimeout)}}
+	defer useFakeTVs(fakes[0], fakes[1])()
+	defer useTestScenes(t, `
+close:
+  - tvs: [fake0, fake1]
+    do: volume 0
+    on_error: continue
+  - tvs: [fake1]
+    do: power off
+  - parallel:
+      - {tvs: [fake0], do: power off}
+`)()
+
+	report := scenes["close"].run(context.Background(), "test", nil)
+	if report.Status != stepFailed {
+		t.Errorf("Got %s instead of failed!", report.Status)
+	}
+	expect := "1 fake0 done, 1 fake1 failed, 2 fake1 failed, 3.1 fake0 not_run"
+	if got := statuses(report); got != expect {
+		t.Errorf("Got %s instead of %s!", got, expect)
+	}
+	if e := report.Results[1].Error; e == nil || e.Code != codeTimeout {
+		t.Errorf("Got %+v instead of a timeout!", e)
+	}
+	if len(fakes[0].ops) != 1 {
+		t.Errorf("Got %v, with operations after the scene stopped!", fakes[0].ops)
+	}
+}
+
+func TestSceneCanceled(t *testing.T) {
+	fakes := []*fakeTV{{}}
+	defer useFakeTVs(fakes[0])()
+	defer useTestScenes(t, `
+slow:
+  - {tvs: [fake0], do: power on}
+  - wait: 20s
+  - {tvs: [fake0], do: power off}
+`)()
+
+	// The client goes away during the wait.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	report := scenes["slow"].run(ctx, "test", nil)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Took %v to notice the client went away!", elapsed)
+	}
+	expect := "1 fake0 done, 2  not_run, 3 fake0 not_run"
+	if got := statuses(report); got != expect {
+		t.Errorf("Got %s instead of %s!", got, expect)
+	}
+	if len(fakes[0].ops) != 1 {
+		t.Errorf("Got %v, with operations after the client went away!", fakes[0].ops)
+	}
+}
+
+func TestServeScenes(t *testing.T) {
+	defer useFakeTVs(&fakeTV{}, &fakeTV{})()
+	defer useTestTokens(t)()
+	defer useTestScenes(t, `
+quiet:
+  - {tvs: [fake1], do: volume 5}
+dark:
+  - {tvs: [fake0, fake1], do: power off}
+`)()
+
+	for _, test := range []struct {
+		method, path, token string
+		status              int
+	}{
+		{"POST", "/scenes/quiet", "", http.StatusUnauthorized},
+		{"POST", "/scenes/quiet", "bar-secret", http.StatusOK},
+		{"POST", "/scenes/dark", "bar-secret", http.StatusForbidden},
+		{"POST", "/scenes/dark", "admin-secret", http.StatusOK},
+		{"POST", "/scenes/nope", "admin-secret", http.StatusNotFound},
+		{"GET", "/scenes/quiet", "admin-secret", http.StatusMethodNotAllowed},
+	} {
+		r := httptest.NewRequest(test.method, test.path, nil)
+		if test.token != "" {
+			r.Header.Set("Authorization", "Bearer "+test.token)
+		}
+		w := httptest.NewRecorder()
+		serveScenes(w, r)
+		if w.Code != test.status {
+			t.Errorf("Got %d instead of %d for %s %s with %q!", w.Code, test.status, test.method, test.path, test.token)
+		}
+	}
+
+	r := httptest.NewRequest("GET", "/scenes", nil)
+	r.Header.Set("X-API-Key", "bar-secret")
+	w := httptest.NewRecorder()
+	serveScenes(w, r)
+	var names []string
+	if err := json.NewDecoder(w.Body).Decode(&names); err != nil || len(names) != 1 || names[0] != "quiet" {
+		t.Errorf("Got %v, %v instead of the scenes bar-secret may run!", names, err)
+	}
+}
+
+func TestSceneRaw(t *testing.T) {
+	defer useFakeTVs(&fakeTV{})()
+	tvConfigs[0].V.AllowRaw = true
+	defer useTestScenes(t, `
+reset:
+  - {tvs: [fake0], do: raw ka 01 01}
+`)()
+
+	if report := scenes["reset"].run(context.Background(), "test", nil); report.Status != stepDone {
+		t.Errorf("Got %+v instead of a raw command sent!", report)
+	}
+	tvConfigs[0].V.AllowRaw = false
+	if _, err := newScenes(map[string][]SceneStep{"reset": {{TVs: []string{"fake0"}, Do: "raw ka 01 01"}}}); err == nil || !strings.Contains(err.Error(), errRawDisabled.Error()) {
+		t.Errorf("Got %v instead of raw commands being disabled!", err)
+	}
+}
+
+func TestScenePanic(t *testing.T) {
+	fake := &fakeTV{}
+	defer useFakeTVs(fake, &panicTV{})()
+	defer useTestScenes(t, `
+crash:
+  - parallel:
+      - {tvs: [fake0, fake1], do: power on}
+`)()
+
+	report := scenes["crash"].run(context.Background(), "test", nil)
+	expect := "1.1 fake0 done, 1.1 fake1 failed"
+	if got := statuses(report); got != expect {
+		t.Errorf("Got %s instead of %s!", got, expect)
+	}
+	if e := report.Results[1].Error; report.Status != stepFailed || e == nil || e.Code != codeInternal {
+		t.Errorf("Got %+v instead of an internal error!", report)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// certReloader serves a certificate, and optionally a client CA bundle,
+// from files that may be replaced while the server runs. The files are
+// checked before every handshake and reloaded when they change; if a
+// reload fails, the previous certificate stays in use.
+type certReloader struct {
+	certFile, keyFile, caFile string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	clients *x509.CertPool
+	modTime map[string]time.Time
+}
+
+func newCertReloader(certFile, keyFile, caFile string) (*certReloader, error) {
+	c := &certReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		caFile:   caFile,
+		modTime:  make(map[string]time.Time),
+	}
+	// Note the files' state before reading them, so that a change made
+	// while they are read is picked up by the next handshake.
+	c.changed()
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *certReloader) files() []string {
+	files := []string{c.certFile, c.keyFile}
+	if c.caFile != "" {
+		files = append(files, c.caFile)
+	}
+	return files
+}
+
+func (c *certReloader) load() error {
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return err
+	}
+
+	var clients *x509.CertPool
+	if c.caFile != "" {
+		pem, err := ioutil.ReadFile(c.caFile)
+		if err != nil {
+			return err
+		}
+		clients = x509.NewCertPool()
+		if !clients.AppendCertsFromPEM(pem) {
+			return errors.New(c.caFile + ": no certificates found")
+		}
+	}
+
+	c.cert, c.clients = &cert, clients
+	return nil
+}
+
+// changed reports whether any of the files has been modified since it was
+// last seen.
+func (c *certReloader) changed() bool {
+	changed := false
+	for _, f := range c.files() {
+		fi, err := os.Stat(f)
+		if err != nil {
+			continue
+		}
+		if !fi.ModTime().Equal(c.modTime[f]) {
+			c.modTime[f] = fi.ModTime()
+			changed = true
+		}
+	}
+	return changed
+}
+
+func (c *certReloader) configForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.changed() {
+		if err := c.load(); err != nil {
+			log.Printf("failed to reload TLS certificate: %v\n", err)
+		}
+	}
+
+	cfg := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{*c.cert},
+	}
+	if c.clients != nil {
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+		cfg.ClientCAs = c.clients
+	}
+	return cfg, nil
+}
+
+func (c *certReloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		GetConfigForClient: c.configForClient,
+	}
+}
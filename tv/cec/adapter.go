@@ -0,0 +1,11 @@
+package cec
+
+// Adapter is a physical connection to the CEC bus.
+type Adapter interface {
+	// Transmit sends a single frame, returning once the adapter has
+	// accepted it.
+	Transmit(Frame) error
+	// Receive blocks until a frame arrives from the bus.
+	Receive() (Frame, error)
+	Close() error
+}
@@ -0,0 +1,189 @@
+// Package cec drives televisions over HDMI-CEC. The protocol layer is
+// independent of the hardware, which is reached through an Adapter.
+package cec
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+
+	"github.com/DHowett/avantgarde/tv"
+)
+
+type Config struct {
+	// LogicalAddress is the address avantgarde claims on the bus.
+	LogicalAddress uint8 `yaml:"logical_address"`
+}
+
+func (c Config) ModelSpecificRepresentation() interface{} {
+	return c
+}
+
+type cecModel struct{}
+
+func (l *cecModel) Initialize(rwc io.ReadWriteCloser, c tv.Config) (tv.TV, error) {
+	cecc, ok := c.(*Config)
+	if !ok {
+		return nil, fmt.Errorf("cec: invalid config type %T", c)
+	}
+
+	if rwc == nil {
+		return nil, errors.New("cec: no adapter serial port configured")
+	}
+
+	la := LogicalAddress(cecc.LogicalAddress)
+	adapter, err := NewPulse8Adapter(rwc, la)
+	if err != nil {
+		return nil, err
+	}
+	return newCECTV(adapter, la), nil
+}
+
+func (l *cecModel) NewConfig() tv.Config {
+	return &Config{LogicalAddress: uint8(RecordingDev1)}
+}
+
+type cecTV struct {
+	adapter Adapter
+	la      LogicalAddress
+
+	mu    sync.Mutex
+	state tv.State
+	// systemAudio is set while an audio system has taken over the TV's
+	// speakers, in which case it should receive volume keys.
+	systemAudio bool
+}
+
+func newCECTV(adapter Adapter, la LogicalAddress) *cecTV {
+	cec := &cecTV{
+		adapter: adapter,
+		la:      la,
+	}
+	go cec.run()
+	cec.adapter.Transmit(Frame{la, TV, OpGiveDevicePowerStatus, nil})
+	return cec
+}
+
+// handle folds a frame received from the bus into the TV's state.
+func (cec *cecTV) handle(f Frame) {
+	cec.mu.Lock()
+	defer cec.mu.Unlock()
+
+	if f.Opcode == OpSetSystemAudioMode && len(f.Operands) > 0 {
+		cec.systemAudio = f.Operands[0] == 0x01
+		return
+	}
+
+	op := Decode(f)
+	if op == nil || !cec.concernsTV(f) {
+		return
+	}
+	switch op.Attribute {
+	case tv.Power:
+		cec.state.Power = op.Value.(bool)
+	case tv.Mute:
+		if op.Operator == tv.Toggle {
+			cec.state.Mute = !cec.state.Mute
+		} else {
+			cec.state.Mute = op.Value.(bool)
+		}
+	}
+}
+
+// concernsTV reports whether a frame tells us about the TV itself, rather
+// than about some other device on the bus.
+func (cec *cecTV) concernsTV(f Frame) bool {
+	switch f.Opcode {
+	case OpReportPowerStatus:
+		// Every device reports its own power status.
+		return f.Initiator == TV
+	case OpImageViewOn, OpStandby:
+		return f.Destination == TV || f.Destination == Broadcast
+	case OpUserControlPressed:
+		// Only keys sent to the TV change it. Keys sent to other devices,
+		// like mute to an audio system, and those the TV forwards to us
+		// from its remote leave it as it was.
+		return f.Destination == TV
+	}
+	return true
+}
+
+func (cec *cecTV) Do(op *tv.Op) error {
+	if op.Attribute == tv.Raw {
+		// Raw commands are frames in cec-client notation, e.g. "10:04".
+		f, err := ParseFrameString(string(op.Value.([]byte)))
+		if err != nil {
+			return fmt.Errorf("%v: %w", err, tv.ErrInvalidValue)
+		}
+		return cec.adapter.Transmit(f)
+	}
+
+	cec.mu.Lock()
+	audio := TV
+	if cec.systemAudio {
+		audio = AudioSystem
+	}
+	cec.mu.Unlock()
+
+	frames, err := Encode(op, cec.la, audio)
+	if err != nil {
+		return err
+	}
+	for _, f := range frames {
+		if err := cec.adapter.Transmit(f); err != nil {
+			return err
+		}
+	}
+
+	// The bus does not announce these changes to us, so assume they took.
+	cec.mu.Lock()
+	defer cec.mu.Unlock()
+	switch op.Attribute {
+	case tv.Power:
+		if op.Operator == tv.Set {
+			cec.state.Power = op.Value.(bool)
+		}
+	case tv.Mute:
+		if op.Operator == tv.Set {
+			cec.state.Mute = op.Value.(bool)
+		} else {
+			cec.state.Mute = !cec.state.Mute
+		}
+	}
+	return nil
+}
+
+func (cec *cecTV) State() (*tv.State, error) {
+	cec.mu.Lock()
+	defer cec.mu.Unlock()
+	state := cec.state
+	return &state, nil
+}
+
+func (cec *cecTV) run() {
+	for {
+		f, err := cec.adapter.Receive()
+		if err != nil {
+			log.Printf("cec: adapter failed: %v", err)
+			return
+		}
+		cec.handle(f)
+	}
+}
+
+var capabilities = []tv.Capability{
+	{Attribute: tv.Power, Operators: []tv.Operator{tv.Set, tv.Query}},
+	{Attribute: tv.Volume, Operators: []tv.Operator{tv.Increment, tv.Decrement}},
+	{Attribute: tv.Mute, Operators: []tv.Operator{tv.Set, tv.Toggle}},
+	{Attribute: tv.Raw, Operators: []tv.Operator{tv.Set}},
+}
+
+func (cec *cecTV) Capabilities() []tv.Capability {
+	return capabilities
+}
+
+func init() {
+	tv.RegisterModel("cec", &cecModel{})
+}
@@ -0,0 +1,230 @@
+package cec
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/DHowett/avantgarde/tv"
+)
+
+// fakeAdapter hands every transmitted frame to the test and delivers
+// whatever the test injects as received frames.
+type fakeAdapter struct {
+	sent chan Frame
+	recv chan Frame
+}
+
+func newFakeAdapter() *fakeAdapter {
+	return &fakeAdapter{
+		sent: make(chan Frame, 16),
+		recv: make(chan Frame, 16),
+	}
+}
+
+func (f *fakeAdapter) Transmit(fr Frame) error {
+	f.sent <- fr
+	return nil
+}
+
+func (f *fakeAdapter) Receive() (Frame, error) {
+	fr, ok := <-f.recv
+	if !ok {
+		return Frame{}, io.EOF
+	}
+	return fr, nil
+}
+
+func (f *fakeAdapter) Close() error {
+	close(f.recv)
+	return nil
+}
+
+func (f *fakeAdapter) inject(t *testing.T, s string) {
+	fr, err := ParseFrameString(s)
+	if err != nil {
+		t.Fatalf("Failed to parse %s: %v", s, err)
+	}
+	f.recv <- fr
+}
+
+func (f *fakeAdapter) expect(t *testing.T, s ...string) {
+	for _, want := range s {
+		select {
+		case fr := <-f.sent:
+			if fr.String() != want {
+				t.Errorf("Got %v instead of %s!", fr, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for %s", want)
+		}
+	}
+}
+
+func TestSerialization(t *testing.T) {
+	f := Frame{RecordingDev1, TV, OpImageViewOn, nil}
+
+	expect := []byte{0x10, 0x04}
+	if !bytes.Equal(f.Bytes(), expect) {
+		t.Errorf("Got %x instead of %x for serializing %v!", f.Bytes(), expect, f)
+	}
+
+	f = SetSystemAudioMode(AudioSystem, true)
+	if f.String() != "5f:72:01" {
+		t.Errorf("Got %v instead of 5f:72:01!", f)
+	}
+
+	f, err := ParseFrameString("01:90:00")
+	if err != nil || f.Initiator != TV || f.Destination != RecordingDev1 || f.Opcode != OpReportPowerStatus {
+		t.Errorf("Got %v (%v) instead of a power status report from the TV!", f, err)
+	}
+
+	if _, err = ParseFrameString("10"); err != errPollFrame {
+		t.Errorf("Got %v instead of a polling message error!", err)
+	}
+}
+
+func waitForState(t *testing.T, cec *cecTV, cond func(*tv.State) bool) {
+	deadline := time.Now().Add(time.Second)
+	for {
+		state, _ := cec.State()
+		if cond(state) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for state; last was %+v", state)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestTV(t *testing.T) {
+	adapter := newFakeAdapter()
+	defer adapter.Close()
+
+	cec := newCECTV(adapter, RecordingDev1)
+	adapter.expect(t, "10:8f")
+
+	adapter.inject(t, "01:90:00")
+	waitForState(t, cec, func(s *tv.State) bool { return s.Power })
+
+	cec.Do(&tv.Op{tv.Power, tv.Set, true})
+	adapter.expect(t, "10:04")
+
+	cec.Do(&tv.Op{tv.Volume, tv.Increment, 1})
+	adapter.expect(t, "10:44:41", "10:45")
+
+	// Once an audio system takes over, volume keys go to it instead.
+	adapter.inject(t, "5f:72:01")
+	waitForState(t, cec, func(*tv.State) bool {
+		cec.mu.Lock()
+		defer cec.mu.Unlock()
+		return cec.systemAudio
+	})
+	cec.Do(&tv.Op{tv.Mute, tv.Set, true})
+	adapter.expect(t, "15:44:65", "15:45")
+
+	cec.Do(&tv.Op{tv.Raw, tv.Set, []byte("1f:36")})
+	adapter.expect(t, "1f:36")
+
+	if err := cec.Do(&tv.Op{tv.Volume, tv.Set, 10}); err != errUnsupported {
+		t.Errorf("Got %v instead of unsupported for an absolute volume!", err)
+	}
+
+	// Other devices' power reports, keys sent to other devices and keys
+	// the TV forwards to us are not about the TV. The audio system leaving
+	// marks when they have all been handled.
+	adapter.inject(t, "41:90:01")
+	adapter.inject(t, "05:44:43")
+	adapter.inject(t, "01:44:43")
+	adapter.inject(t, "5f:72:00")
+	waitForState(t, cec, func(*tv.State) bool {
+		cec.mu.Lock()
+		defer cec.mu.Unlock()
+		return !cec.systemAudio
+	})
+	state, _ := cec.State()
+	if !state.Power {
+		t.Errorf("A playback device's standby turned the TV off!")
+	}
+	if !state.Mute {
+		t.Errorf("A key that was not sent to the TV unmuted it!")
+	}
+
+	// A key sent to the TV does change it.
+	adapter.inject(t, "40:44:43")
+	waitForState(t, cec, func(s *tv.State) bool { return !s.Mute })
+
+	adapter.inject(t, "0f:36")
+	waitForState(t, cec, func(s *tv.State) bool { return !s.Power })
+}
+
+func TestPulse8(t *testing.T) {
+	host, dev := net.Pipe()
+	defer host.Close()
+	defer dev.Close()
+
+	dr := bufio.NewReader(dev)
+	readMsg := func() p8Message {
+		m, err := readP8Message(dr)
+		if err != nil {
+			t.Fatalf("Failed to read from adapter: %v", err)
+		}
+		return m
+	}
+
+	adapterCh := make(chan Adapter)
+	go func() {
+		p8, err := NewPulse8Adapter(host, RecordingDev1)
+		if err != nil {
+			t.Errorf("Failed to set up adapter: %v", err)
+		}
+		adapterCh <- p8
+	}()
+
+	if m := readMsg(); m.code != p8SetAckMask || !bytes.Equal(m.params, []byte{0x00, 0x02}) {
+		t.Errorf("Got %v instead of an ack mask for address 1!", m)
+	}
+	p8 := <-adapterCh
+
+	// A frame is reported one block per message, the last marked EOM.
+	go dev.Write([]byte{
+		p8MsgStart, p8FrameStart, 0x01, p8MsgEnd,
+		p8MsgStart, p8FrameData, 0x90, p8MsgEnd,
+		p8MsgStart, p8FrameData | p8FlagEOM, 0x00, p8MsgEnd,
+	})
+	f, err := p8.Receive()
+	if err != nil || f.String() != "01:90:00" {
+		t.Errorf("Got %v (%v) instead of 01:90:00!", f, err)
+	}
+
+	errCh := make(chan error)
+	go func() {
+		errCh <- p8.Transmit(Frame{RecordingDev1, TV, OpImageViewOn, nil})
+	}()
+	for _, want := range []p8Message{
+		{p8TransmitAckPolarity, []byte{0}},
+		{p8Transmit, []byte{0x10}},
+		{p8TransmitEOM, []byte{0x04}},
+	} {
+		if m := readMsg(); m.code != want.code || !bytes.Equal(m.params, want.params) {
+			t.Errorf("Got %v instead of %v!", m, want)
+		}
+	}
+	dev.Write(p8Message{p8TransmitSucceeded, nil}.Serialize())
+	if err := <-errCh; err != nil {
+		t.Errorf("Transmit failed: %v", err)
+	}
+
+	escaped := p8Message{p8FrameData, []byte{0xFF}}.Serialize()
+	if !bytes.Equal(escaped, []byte{p8MsgStart, p8FrameData, p8MsgEsc, 0xFC, p8MsgEnd}) {
+		t.Errorf("Got %x for a message needing escapes!", escaped)
+	}
+	m, err := readP8Message(bufio.NewReader(bytes.NewReader(escaped)))
+	if err != nil || !bytes.Equal(m.params, []byte{0xFF}) {
+		t.Errorf("Got %v (%v) instead of ff when unescaping!", m, err)
+	}
+}
@@ -0,0 +1,108 @@
+package cec
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type LogicalAddress uint8
+
+const (
+	TV            LogicalAddress = 0x0
+	RecordingDev1 LogicalAddress = 0x1
+	PlaybackDev1  LogicalAddress = 0x4
+	AudioSystem   LogicalAddress = 0x5
+	Broadcast     LogicalAddress = 0xF
+)
+
+type Opcode uint8
+
+const (
+	OpImageViewOn           Opcode = 0x04
+	OpStandby               Opcode = 0x36
+	OpUserControlPressed    Opcode = 0x44
+	OpUserControlReleased   Opcode = 0x45
+	OpSetSystemAudioMode    Opcode = 0x72
+	OpGiveDevicePowerStatus Opcode = 0x8F
+	OpReportPowerStatus     Opcode = 0x90
+)
+
+// UI command codes carried by User Control Pressed.
+const (
+	UIPower                 byte = 0x40
+	UIVolumeUp              byte = 0x41
+	UIVolumeDown            byte = 0x42
+	UIMute                  byte = 0x43
+	UIMuteFunction          byte = 0x65
+	UIRestoreVolumeFunction byte = 0x66
+	UIPowerOffFunction      byte = 0x6C
+	UIPowerOnFunction       byte = 0x6D
+)
+
+// Operands of Report Power Status.
+const (
+	PowerStatusOn                  byte = 0x00
+	PowerStatusStandby             byte = 0x01
+	PowerStatusTransitionToOn      byte = 0x02
+	PowerStatusTransitionToStandby byte = 0x03
+)
+
+// Frame is a single CEC message. Polling messages, which consist of a header
+// block alone, are not represented.
+type Frame struct {
+	Initiator   LogicalAddress
+	Destination LogicalAddress
+	Opcode      Opcode
+	Operands    []byte
+}
+
+func (f Frame) Bytes() []byte {
+	b := make([]byte, 0, 2+len(f.Operands))
+	b = append(b, byte(f.Initiator)<<4|byte(f.Destination&0x0F), byte(f.Opcode))
+	return append(b, f.Operands...)
+}
+
+// String renders the frame in the colon-separated hex notation used by
+// libcec's cec-client, e.g. "10:04".
+func (f Frame) String() string {
+	b := f.Bytes()
+	parts := make([]string, len(b))
+	for i, v := range b {
+		parts[i] = fmt.Sprintf("%02x", v)
+	}
+	return strings.Join(parts, ":")
+}
+
+var (
+	errPollFrame  = errors.New("cec: polling message")
+	errShortFrame = errors.New("cec: empty frame")
+	errLongFrame  = errors.New("cec: frame longer than 16 blocks")
+)
+
+func ParseFrame(b []byte) (Frame, error) {
+	switch {
+	case len(b) == 0:
+		return Frame{}, errShortFrame
+	case len(b) == 1:
+		return Frame{}, errPollFrame
+	case len(b) > 16:
+		return Frame{}, errLongFrame
+	}
+	return Frame{
+		Initiator:   LogicalAddress(b[0] >> 4),
+		Destination: LogicalAddress(b[0] & 0x0F),
+		Opcode:      Opcode(b[1]),
+		Operands:    append([]byte(nil), b[2:]...),
+	}, nil
+}
+
+// ParseFrameString parses the colon-separated notation produced by String.
+func ParseFrameString(s string) (Frame, error) {
+	b, err := hex.DecodeString(strings.Replace(strings.TrimSpace(s), ":", "", -1))
+	if err != nil {
+		return Frame{}, fmt.Errorf("cec: invalid frame %q: %v", s, err)
+	}
+	return ParseFrame(b)
+}
@@ -0,0 +1,100 @@
+package cec
+
+import (
+	"fmt"
+
+	"github.com/DHowett/avantgarde/tv"
+)
+
+var errUnsupported = fmt.Errorf("cec: %w", tv.ErrUnsupported)
+
+func userControl(initiator, destination LogicalAddress, key byte) []Frame {
+	return []Frame{
+		{initiator, destination, OpUserControlPressed, []byte{key}},
+		{initiator, destination, OpUserControlReleased, nil},
+	}
+}
+
+// SetSystemAudioMode returns the broadcast an audio system sends to tell
+// the TV whether it has taken over audio output.
+func SetSystemAudioMode(initiator LogicalAddress, on bool) Frame {
+	var mode byte
+	if on {
+		mode = 0x01
+	}
+	return Frame{initiator, Broadcast, OpSetSystemAudioMode, []byte{mode}}
+}
+
+// Encode returns the frames, sent from initiator, that carry out op. Volume
+// and mute keys are sent to audio, which is the TV itself unless an audio
+// system has taken over.
+func Encode(op *tv.Op, initiator, audio LogicalAddress) ([]Frame, error) {
+	switch op.Attribute {
+	case tv.Power:
+		switch op.Operator {
+		case tv.Set:
+			if op.Value.(bool) {
+				return []Frame{{initiator, TV, OpImageViewOn, nil}}, nil
+			}
+			return []Frame{{initiator, TV, OpStandby, nil}}, nil
+		case tv.Query:
+			return []Frame{{initiator, TV, OpGiveDevicePowerStatus, nil}}, nil
+		}
+	case tv.Volume:
+		switch op.Operator {
+		case tv.Increment:
+			return userControl(initiator, audio, UIVolumeUp), nil
+		case tv.Decrement:
+			return userControl(initiator, audio, UIVolumeDown), nil
+		}
+	case tv.Mute:
+		switch op.Operator {
+		case tv.Set:
+			if op.Value.(bool) {
+				return userControl(initiator, audio, UIMuteFunction), nil
+			}
+			return userControl(initiator, audio, UIRestoreVolumeFunction), nil
+		case tv.Toggle:
+			return userControl(initiator, audio, UIMute), nil
+		}
+	}
+	return nil, errUnsupported
+}
+
+// Decode returns the change of state that f announces, or nil if it does
+// not correspond to any tv.Op.
+func Decode(f Frame) *tv.Op {
+	switch f.Opcode {
+	case OpReportPowerStatus:
+		if len(f.Operands) < 1 {
+			return nil
+		}
+		switch f.Operands[0] {
+		case PowerStatusOn, PowerStatusTransitionToOn:
+			return &tv.Op{tv.Power, tv.Set, true}
+		case PowerStatusStandby, PowerStatusTransitionToStandby:
+			return &tv.Op{tv.Power, tv.Set, false}
+		}
+	case OpImageViewOn:
+		return &tv.Op{tv.Power, tv.Set, true}
+	case OpStandby:
+		return &tv.Op{tv.Power, tv.Set, false}
+	case OpUserControlPressed:
+		if len(f.Operands) < 1 {
+			return nil
+		}
+		switch f.Operands[0] {
+		case UIVolumeUp:
+			return &tv.Op{tv.Volume, tv.Increment, 1}
+		case UIVolumeDown:
+			return &tv.Op{tv.Volume, tv.Decrement, 1}
+		case UIMute:
+			return &tv.Op{tv.Mute, tv.Toggle, nil}
+		case UIMuteFunction:
+			return &tv.Op{tv.Mute, tv.Set, true}
+		case UIRestoreVolumeFunction:
+			return &tv.Op{tv.Mute, tv.Set, false}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package cec
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/DHowett/avantgarde/tv"
+)
+
+// Framing used by the Pulse-Eight USB-CEC adapter on its serial interface.
+// Every message is MSGSTART, a code byte, escaped parameters and MSGEND.
+const (
+	p8MsgStart  byte = 0xFF
+	p8MsgEnd    byte = 0xFE
+	p8MsgEsc    byte = 0xFD
+	p8EscOffset byte = 3
+
+	p8FlagEOM  byte = 0x80
+	p8FlagAck  byte = 0x40
+	p8CodeMask byte = 0x3F
+)
+
+const (
+	p8FrameStart                byte = 0x05
+	p8FrameData                 byte = 0x06
+	p8SetAckMask                byte = 0x0A
+	p8Transmit                  byte = 0x0B
+	p8TransmitEOM               byte = 0x0C
+	p8TransmitAckPolarity       byte = 0x0E
+	p8TransmitSucceeded         byte = 0x10
+	p8TransmitFailedLine        byte = 0x11
+	p8TransmitFailedAck         byte = 0x12
+	p8TransmitFailedTimeoutData byte = 0x13
+	p8TransmitFailedTimeoutLine byte = 0x14
+)
+
+const p8TransmitTimeout = time.Second
+
+type p8Message struct {
+	code   byte
+	params []byte
+}
+
+func (m p8Message) Serialize() []byte {
+	b := []byte{p8MsgStart}
+	for _, v := range append([]byte{m.code}, m.params...) {
+		if v >= p8MsgEsc {
+			b = append(b, p8MsgEsc, v-p8EscOffset)
+		} else {
+			b = append(b, v)
+		}
+	}
+	return append(b, p8MsgEnd)
+}
+
+func readP8Message(r io.ByteReader) (p8Message, error) {
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return p8Message{}, err
+		}
+		if c == p8MsgStart {
+			break
+		}
+	}
+
+	var b []byte
+	escaped := false
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return p8Message{}, err
+		}
+		switch {
+		case escaped:
+			b = append(b, c+p8EscOffset)
+			escaped = false
+		case c == p8MsgEsc:
+			escaped = true
+		case c == p8MsgEnd:
+			if len(b) == 0 {
+				return p8Message{}, errors.New("cec: empty Pulse-Eight message")
+			}
+			return p8Message{b[0], b[1:]}, nil
+		case c == p8MsgStart:
+			// A new message began before this one ended; discard it.
+			b = b[:0]
+		default:
+			b = append(b, c)
+		}
+	}
+}
+
+// pulse8Adapter speaks to a Pulse-Eight USB-CEC adapter over its serial port.
+type pulse8Adapter struct {
+	rwc io.ReadWriteCloser
+
+	// txMu holds each transmission until the adapter reports its result.
+	txMu    sync.Mutex
+	results chan byte
+
+	frames chan Frame
+	err    error
+}
+
+// NewPulse8Adapter wraps the adapter's serial port and configures it to
+// acknowledge frames sent to la.
+func NewPulse8Adapter(rwc io.ReadWriteCloser, la LogicalAddress) (Adapter, error) {
+	p8 := &pulse8Adapter{
+		rwc:     rwc,
+		frames:  make(chan Frame, 16),
+		results: make(chan byte, 1),
+	}
+
+	mask := uint16(1) << la
+	if _, err := rwc.Write(p8Message{p8SetAckMask, []byte{byte(mask >> 8), byte(mask)}}.Serialize()); err != nil {
+		return nil, err
+	}
+	go p8.run()
+	return p8, nil
+}
+
+func (p8 *pulse8Adapter) run() {
+	br := bufio.NewReader(p8.rwc)
+	var frame []byte
+	for {
+		msg, err := readP8Message(br)
+		if err != nil {
+			p8.err = err
+			close(p8.frames)
+			return
+		}
+
+		code := msg.code & p8CodeMask
+		switch code {
+		case p8FrameStart, p8FrameData:
+			if code == p8FrameStart {
+				frame = frame[:0]
+			}
+			frame = append(frame, msg.params...)
+			if msg.code&p8FlagEOM != 0 {
+				if f, err := ParseFrame(frame); err == nil {
+					p8.frames <- f
+				}
+			}
+		case p8TransmitSucceeded, p8TransmitFailedLine, p8TransmitFailedAck,
+			p8TransmitFailedTimeoutData, p8TransmitFailedTimeoutLine:
+			select {
+			case p8.results <- code:
+			default:
+			}
+		}
+	}
+}
+
+func (p8 *pulse8Adapter) Transmit(f Frame) error {
+	var polarity byte
+	if f.Destination == Broadcast {
+		// Followers of a broadcast pull the ACK bit low to reject it.
+		polarity = 1
+	}
+
+	buf := p8Message{p8TransmitAckPolarity, []byte{polarity}}.Serialize()
+	b := f.Bytes()
+	for i, v := range b {
+		code := p8Transmit
+		if i == len(b)-1 {
+			code = p8TransmitEOM
+		}
+		buf = append(buf, p8Message{code, []byte{v}}.Serialize()...)
+	}
+
+	p8.txMu.Lock()
+	defer p8.txMu.Unlock()
+
+	// Discard the result of any earlier transmission that timed out.
+	select {
+	case <-p8.results:
+	default:
+	}
+
+	if _, err := p8.rwc.Write(buf); err != nil {
+		return err
+	}
+
+	select {
+	case code := <-p8.results:
+		if code != p8TransmitSucceeded {
+			return fmt.Errorf("cec: transmit of %v failed (%#02x)", f, code)
+		}
+		return nil
+	case <-time.After(p8TransmitTimeout):
+		return fmt.Errorf("cec: transmit of %v %w", f, tv.ErrTimeout)
+	}
+}
+
+func (p8 *pulse8Adapter) Receive() (Frame, error) {
+	f, ok := <-p8.frames
+	if !ok {
+		return Frame{}, p8.err
+	}
+	return f, nil
+}
+
+func (p8 *pulse8Adapter) Close() error {
+	return p8.rwc.Close()
+}
@@ -0,0 +1,314 @@
+package denon
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DHowett/avantgarde/tv"
+)
+
+// Receivers drop commands that arrive less than 50ms after the previous one.
+const commandInterval = 50 * time.Millisecond
+
+type Config struct {
+	Address string
+}
+
+func (c Config) ModelSpecificRepresentation() interface{} {
+	return c
+}
+
+type avrModel struct{}
+
+func (l *avrModel) Initialize(rwc io.ReadWriteCloser, c tv.Config) (tv.TV, error) {
+	avrc, ok := c.(*Config)
+	if !ok {
+		return nil, fmt.Errorf("denon: invalid config type %T", c)
+	}
+
+	if rwc == nil && avrc.Address == "" {
+		return nil, errors.New("denon: neither a serial port nor an address was configured")
+	}
+
+	avr := &denonAVR{
+		config: avrc,
+		serial: rwc,
+	}
+	go avr.run()
+	return avr, nil
+}
+
+func (l *avrModel) NewConfig() tv.Config {
+	return &Config{}
+}
+
+func sourceName(i tv.InputNumber) string {
+	switch i.Connection {
+	case tv.Coaxial:
+		return "TUNER"
+	case tv.HDMI:
+		return fmt.Sprintf("HDMI%d", i.Number)
+	case tv.Special:
+		return fmt.Sprintf("AUX%d", i.Number)
+	}
+	return ""
+}
+
+func sourceInput(source string) (tv.InputNumber, bool) {
+	number := func(prefix string) int {
+		n, err := strconv.Atoi(strings.TrimPrefix(source, prefix))
+		if err != nil {
+			return 1
+		}
+		return n
+	}
+
+	switch {
+	case source == "TUNER":
+		return tv.InputNumber{tv.Coaxial, 1}, true
+	case strings.HasPrefix(source, "HDMI"):
+		return tv.InputNumber{tv.HDMI, number("HDMI")}, true
+	case strings.HasPrefix(source, "AUX"):
+		return tv.InputNumber{tv.Special, number("AUX")}, true
+	}
+	return tv.InputNumber{}, false
+}
+
+// parseVolume decodes a master volume parameter; a third digit denotes a
+// half step, so "455" is 45.5, which we round down.
+func parseVolume(s string) (int, bool) {
+	if len(s) < 2 || len(s) > 3 {
+		return 0, false
+	}
+	v, err := strconv.Atoi(s[:2])
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
+
+func clamp(val int) int {
+	switch {
+	case val < 0:
+		return 0
+	case val > maxVolume:
+		return maxVolume
+	default:
+		return val
+	}
+}
+
+type denonAVR struct {
+	config *Config
+	serial io.ReadWriteCloser
+
+	// sendMu keeps commands at least commandInterval apart.
+	sendMu sync.Mutex
+
+	mu    sync.Mutex
+	w     io.Writer
+	state tv.State
+}
+
+func (avr *denonAVR) send(cmd, param string) error {
+	avr.sendMu.Lock()
+	defer avr.sendMu.Unlock()
+
+	avr.mu.Lock()
+	w := avr.w
+	avr.mu.Unlock()
+	if w == nil {
+		return fmt.Errorf("denon: receiver %w", tv.ErrDisconnected)
+	}
+
+	if _, err := io.WriteString(w, cmd+param+"\r"); err != nil {
+		return err
+	}
+	time.Sleep(commandInterval)
+	return nil
+}
+
+// parseLine folds a single status line, whether solicited or not, into the
+// receiver's state.
+func (avr *denonAVR) parseLine(line string) {
+	if len(line) < 3 {
+		return
+	}
+
+	avr.mu.Lock()
+	defer avr.mu.Unlock()
+
+	cmd, param := line[:2], line[2:]
+	switch cmd {
+	case cmdPower:
+		avr.state.Power = param == paramOn
+	case cmdVolume:
+		if strings.HasPrefix(param, paramVolumeMax) {
+			return
+		}
+		if v, ok := parseVolume(param); ok {
+			avr.state.Volume = v
+		}
+	case cmdMute:
+		avr.state.Mute = param == paramOn
+	case cmdInput:
+		if in, ok := sourceInput(param); ok {
+			avr.state.Input = in
+		}
+	}
+}
+
+func (avr *denonAVR) Do(op *tv.Op) error {
+	var cmd, param string
+	switch op.Attribute {
+	case tv.Power:
+		cmd, param = cmdPower, paramStandby
+		if op.Value.(bool) {
+			param = paramOn
+		}
+	case tv.Volume:
+		cmd = cmdVolume
+		switch op.Operator {
+		case tv.Set:
+			param = fmt.Sprintf("%02d", clamp(op.Value.(int)))
+		case tv.Increment:
+			param = paramUp
+		case tv.Decrement:
+			param = paramDown
+		}
+	case tv.Mute:
+		var mute bool
+		switch op.Operator {
+		case tv.Set:
+			mute = op.Value.(bool)
+		case tv.Toggle:
+			avr.mu.Lock()
+			mute = !avr.state.Mute
+			avr.mu.Unlock()
+		}
+		cmd, param = cmdMute, paramOff
+		if mute {
+			param = paramOn
+		}
+	case tv.Input:
+		if source := sourceName(op.Value.(tv.InputNumber)); source != "" {
+			cmd, param = cmdInput, source
+		}
+	case tv.Raw:
+		raw := strings.TrimRight(string(op.Value.([]byte)), "\r")
+		if !validRaw(raw) {
+			return fmt.Errorf("denon: raw commands must be 2 to %d printable characters: %w", maxRawLength, tv.ErrInvalidValue)
+		}
+		return avr.send(raw, "")
+	}
+
+	if cmd == "" || param == "" {
+		return fmt.Errorf("denon: %w", tv.ErrUnsupported)
+	}
+	return avr.send(cmd, param)
+}
+
+// Commands are at most 135 characters, including the carriage return.
+const maxRawLength = 134
+
+func validRaw(raw string) bool {
+	if len(raw) < 2 || len(raw) > maxRawLength {
+		return false
+	}
+	for _, c := range raw {
+		if c < 0x20 || c > 0x7E {
+			return false
+		}
+	}
+	return true
+}
+
+func (avr *denonAVR) State() (*tv.State, error) {
+	avr.mu.Lock()
+	defer avr.mu.Unlock()
+	state := avr.state
+	return &state, nil
+}
+
+func (avr *denonAVR) connect() (io.ReadWriteCloser, error) {
+	if avr.serial != nil {
+		return avr.serial, nil
+	}
+	addr := avr.config.Address
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "23")
+	}
+	return net.DialTimeout("tcp", addr, 10*time.Second)
+}
+
+func (avr *denonAVR) session(rwc io.ReadWriteCloser) error {
+	avr.mu.Lock()
+	avr.w = rwc
+	avr.mu.Unlock()
+
+	defer func() {
+		avr.mu.Lock()
+		avr.w = nil
+		avr.mu.Unlock()
+	}()
+
+	go func() {
+		for _, cmd := range []string{cmdPower, cmdVolume, cmdMute, cmdInput} {
+			avr.send(cmd, paramQuery)
+		}
+	}()
+
+	br := bufio.NewReader(rwc)
+	for {
+		line, err := br.ReadString('\r')
+		if err != nil {
+			return err
+		}
+		avr.parseLine(strings.TrimSpace(line))
+	}
+}
+
+func (avr *denonAVR) run() {
+	for {
+		rwc, err := avr.connect()
+		if err != nil {
+			time.Sleep(5 * time.Second)
+			continue
+		}
+
+		avr.session(rwc)
+		if avr.serial == nil {
+			rwc.Close()
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+var capabilities = []tv.Capability{
+	{Attribute: tv.Power, Operators: []tv.Operator{tv.Set}},
+	{Attribute: tv.Volume, Operators: []tv.Operator{tv.Set, tv.Increment, tv.Decrement}, Min: 0, Max: maxVolume},
+	{Attribute: tv.Mute, Operators: []tv.Operator{tv.Set, tv.Toggle}},
+	{Attribute: tv.Input, Operators: []tv.Operator{tv.Set}},
+	{Attribute: tv.Raw, Operators: []tv.Operator{tv.Set}},
+}
+
+func (avr *denonAVR) Capabilities() []tv.Capability {
+	return capabilities
+}
+
+func (avr *denonAVR) Connected() bool {
+	avr.mu.Lock()
+	defer avr.mu.Unlock()
+	return avr.w != nil
+}
+
+func init() {
+	tv.RegisterModel("denon", &avrModel{})
+}
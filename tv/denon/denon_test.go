@@ -0,0 +1,65 @@
+package denon
+
+import "bufio"
+import "net"
+import "strings"
+import "testing"
+import "time"
+
+import "github.com/DHowett/avantgarde/tv"
+
+func TestParseLine(t *testing.T) {
+	avr := &denonAVR{}
+	for _, line := range []string{"PWON", "MV455", "MVMAX 98", "MUON", "SIHDMI2"} {
+		avr.parseLine(line)
+	}
+
+	expect := tv.State{
+		Power:  true,
+		Volume: 45,
+		Mute:   true,
+		Input:  tv.InputNumber{tv.HDMI, 2},
+	}
+	if avr.state != expect {
+		t.Errorf("Got %+v instead of %+v!", avr.state, expect)
+	}
+
+	avr.parseLine("PWSTANDBY")
+	avr.parseLine("MUOFF")
+	if avr.state.Power || avr.state.Mute {
+		t.Errorf("Got %+v after standby and unmute!", avr.state)
+	}
+}
+
+func TestAddress(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// The address carries its own port, which is used instead of 23.
+	avr, err := (&avrModel{}).Initialize(nil, &Config{Address: l.Addr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// The receiver is asked for its state as soon as it is connected.
+	if query, _ := bufio.NewReader(conn).ReadString('\r'); strings.TrimSpace(query) != "PW?" {
+		t.Errorf("Got %q instead of a power query!", query)
+	}
+	conn.Write([]byte("PWON\r"))
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+		if state, _ := avr.State(); state.Power {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("The receiver's power report was not read!")
+		}
+	}
+}
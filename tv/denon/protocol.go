@@ -0,0 +1,24 @@
+package denon
+
+const (
+	cmdPower  = `PW`
+	cmdVolume = `MV`
+	cmdMute   = `MU`
+	cmdInput  = `SI`
+)
+
+const (
+	paramOn      = `ON`
+	paramOff     = `OFF`
+	paramStandby = `STANDBY`
+	paramUp      = `UP`
+	paramDown    = `DOWN`
+	paramQuery   = `?`
+)
+
+// Volume events carry a second line announcing the volume ceiling, e.g.
+// "MVMAX 98", which must not be mistaken for the volume itself.
+const paramVolumeMax = `MAX`
+
+// Receivers take volume in the range 0-98, where 80 is 0dB.
+const maxVolume = 98
@@ -0,0 +1,368 @@
+// Package generic drives displays with simple line-based ASCII protocols
+// described entirely in configuration.
+package generic
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/DHowett/avantgarde/tv"
+)
+
+// StateQuery describes how to read one attribute back from the display: the
+// command to send and a pattern whose first group captures the value.
+type StateQuery struct {
+	Query   string
+	Pattern string
+}
+
+type Config struct {
+	// Transport is "tcp", which dials Address, or "serial", which uses the
+	// TV's configured port.
+	Transport string
+	Address   string
+	// Terminator ends every reply from the display.
+	Terminator string
+	// Ack and Error match replies that report success or failure. If both
+	// are empty, commands are sent without waiting for a reply.
+	Ack   string
+	Error string
+	// Timeout bounds the wait for a reply on network transports.
+	Timeout time.Duration
+	// Commands maps attribute names to operator names to command templates,
+	// e.g. power: {set: "PWR {{if .Value}}1{{else}}0{{end}}\r"}.
+	Commands map[string]map[string]string
+	State    map[string]StateQuery
+}
+
+func (c Config) ModelSpecificRepresentation() interface{} {
+	return c
+}
+
+type genericModel struct{}
+
+func (l *genericModel) Initialize(rwc io.ReadWriteCloser, c tv.Config) (tv.TV, error) {
+	genericc, ok := c.(*Config)
+	if !ok {
+		return nil, fmt.Errorf("generic: invalid config type %T", c)
+	}
+
+	g := &genericTV{
+		config:   genericc,
+		commands: make(map[tv.Attribute]map[tv.Operator]*template.Template),
+		state:    make(map[tv.Attribute]*stateQuery),
+	}
+
+	switch genericc.Transport {
+	case "tcp":
+		if genericc.Address == "" {
+			return nil, errors.New("generic: tcp transport needs an address")
+		}
+	case "serial":
+		if rwc == nil {
+			return nil, errors.New("generic: serial transport needs a port")
+		}
+		g.attach(rwc)
+	default:
+		return nil, fmt.Errorf("generic: unknown transport %q", genericc.Transport)
+	}
+
+	if err := g.compile(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func (l *genericModel) NewConfig() tv.Config {
+	return &Config{
+		Transport:  "tcp",
+		Terminator: "\r",
+		Timeout:    2 * time.Second,
+	}
+}
+
+type stateQuery struct {
+	query   []byte
+	pattern *regexp.Regexp
+}
+
+// templateData is what command templates are executed against.
+type templateData struct {
+	Attribute tv.Attribute
+	Operator  tv.Operator
+	Value     interface{}
+}
+
+type genericTV struct {
+	config   *Config
+	ack      *regexp.Regexp
+	nack     *regexp.Regexp
+	commands map[tv.Attribute]map[tv.Operator]*template.Template
+	state    map[tv.Attribute]*stateQuery
+	// capabilities is derived from commands once they are compiled.
+	capabilities []tv.Capability
+
+	mu sync.Mutex
+	r  *bufio.Reader
+	w  io.Writer
+	c  io.Closer
+}
+
+// compile checks every template and pattern in the configuration up front,
+// so that a mistake is reported at startup rather than on first use.
+func (g *genericTV) compile() error {
+	var err error
+	if g.config.Ack != "" {
+		if g.ack, err = regexp.Compile(g.config.Ack); err != nil {
+			return fmt.Errorf("generic: ack: %v", err)
+		}
+	}
+	if g.config.Error != "" {
+		if g.nack, err = regexp.Compile(g.config.Error); err != nil {
+			return fmt.Errorf("generic: error: %v", err)
+		}
+	}
+
+	for attrName, ops := range g.config.Commands {
+		attr, err := tv.ParseAttribute(attrName)
+		if err != nil {
+			return fmt.Errorf("generic: %v", err)
+		}
+		g.commands[attr] = make(map[tv.Operator]*template.Template)
+		for opName, text := range ops {
+			op, err := tv.ParseOperator(opName)
+			if err != nil {
+				return fmt.Errorf("generic: %s: %v", attrName, err)
+			}
+			tmpl, err := template.New(attrName + "." + opName).Parse(text)
+			if err != nil {
+				return fmt.Errorf("generic: %v", err)
+			}
+			g.commands[attr][op] = tmpl
+		}
+	}
+
+	for attrName, q := range g.config.State {
+		attr, err := tv.ParseAttribute(attrName)
+		if err != nil {
+			return fmt.Errorf("generic: state: %v", err)
+		}
+		pattern, err := regexp.Compile(q.Pattern)
+		if err != nil {
+			return fmt.Errorf("generic: state: %s: %v", attrName, err)
+		}
+		if pattern.NumSubexp() < 1 {
+			return fmt.Errorf("generic: state: %s: pattern captures no value", attrName)
+		}
+		g.state[attr] = &stateQuery{[]byte(q.Query), pattern}
+	}
+
+	for attr, ops := range g.commands {
+		c := tv.Capability{Attribute: attr}
+		for op := range ops {
+			c.Operators = append(c.Operators, op)
+		}
+		sort.Slice(c.Operators, func(i, j int) bool { return c.Operators[i] < c.Operators[j] })
+		g.capabilities = append(g.capabilities, c)
+	}
+	g.capabilities = append(g.capabilities, tv.Capability{Attribute: tv.Raw, Operators: []tv.Operator{tv.Set}})
+	sort.Slice(g.capabilities, func(i, j int) bool { return g.capabilities[i].Attribute < g.capabilities[j].Attribute })
+	return nil
+}
+
+func (g *genericTV) attach(rwc io.ReadWriteCloser) {
+	g.r = bufio.NewReader(rwc)
+	g.w = rwc
+	g.c = rwc
+}
+
+func (g *genericTV) connect() error {
+	if g.r != nil {
+		return nil
+	}
+	conn, err := net.DialTimeout("tcp", g.config.Address, 10*time.Second)
+	if err != nil {
+		return err
+	}
+	g.attach(conn)
+	return nil
+}
+
+func (g *genericTV) disconnect() {
+	if g.config.Transport != "tcp" {
+		return
+	}
+	g.c.Close()
+	g.r, g.w, g.c = nil, nil, nil
+}
+
+func (g *genericTV) readReply() (string, error) {
+	if conn, ok := g.c.(net.Conn); ok && g.config.Timeout > 0 {
+		conn.SetReadDeadline(time.Now().Add(g.config.Timeout))
+		defer conn.SetReadDeadline(time.Time{})
+	}
+
+	term := g.config.Terminator
+	if term == "" {
+		term = "\n"
+	}
+	var buf []byte
+	for {
+		b, err := g.r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		buf = append(buf, b)
+		if bytes.HasSuffix(buf, []byte(term)) {
+			return strings.TrimSpace(string(buf[:len(buf)-len(term)])), nil
+		}
+	}
+}
+
+// send writes a command and, if wantReply is set, returns the display's
+// reply. Replies matching the configured error pattern become errors.
+func (g *genericTV) send(cmd []byte, wantReply bool) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.connect(); err != nil {
+		return "", err
+	}
+	if _, err := g.w.Write(cmd); err != nil {
+		g.disconnect()
+		return "", err
+	}
+	if !wantReply {
+		return "", nil
+	}
+
+	for {
+		reply, err := g.readReply()
+		if err != nil {
+			g.disconnect()
+			return "", err
+		}
+		if g.nack != nil && g.nack.MatchString(reply) {
+			return "", fmt.Errorf("generic: display replied %q", reply)
+		}
+		if reply != "" {
+			return reply, nil
+		}
+	}
+}
+
+// DoRaw sends a command as is. The display's reply is only waited for, and
+// returned, when the configuration says how to recognize one.
+func (g *genericTV) DoRaw(cmd []byte) ([]byte, error) {
+	if len(cmd) == 0 {
+		return nil, fmt.Errorf("generic: empty raw command: %w", tv.ErrInvalidValue)
+	}
+	reply, err := g.send(cmd, g.ack != nil || g.nack != nil)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(reply), nil
+}
+
+func (g *genericTV) Do(op *tv.Op) error {
+	if op.Attribute == tv.Raw {
+		_, err := g.DoRaw(op.Value.([]byte))
+		return err
+	}
+
+	tmpl, ok := g.commands[op.Attribute][op.Operator]
+	if !ok {
+		return fmt.Errorf("generic: %w", tv.ErrUnsupported)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, &templateData{op.Attribute, op.Operator, op.Value}); err != nil {
+		return fmt.Errorf("generic: %v", err)
+	}
+
+	reply, err := g.send(buf.Bytes(), g.ack != nil || g.nack != nil)
+	if err != nil {
+		return err
+	}
+	if g.ack != nil && !g.ack.MatchString(reply) {
+		return fmt.Errorf("generic: unexpected reply %q", reply)
+	}
+	return nil
+}
+
+func parseBool(s string) bool {
+	switch strings.ToLower(s) {
+	case "1", "on", "true", "yes":
+		return true
+	}
+	return false
+}
+
+func (g *genericTV) query(attr tv.Attribute) (string, bool, error) {
+	q, ok := g.state[attr]
+	if !ok {
+		return "", false, nil
+	}
+	reply, err := g.send(q.query, true)
+	if err != nil {
+		return "", false, err
+	}
+	m := q.pattern.FindStringSubmatch(reply)
+	if m == nil {
+		return "", false, fmt.Errorf("generic: unexpected reply %q to %s query", reply, attr)
+	}
+	return m[1], true, nil
+}
+
+func (g *genericTV) State() (*tv.State, error) {
+	if len(g.state) == 0 {
+		return nil, fmt.Errorf("generic: %w", tv.ErrUnsupported)
+	}
+
+	state := &tv.State{}
+	for _, attr := range []tv.Attribute{tv.Power, tv.Volume, tv.Mute, tv.Screen, tv.Input} {
+		v, ok, err := g.query(attr)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		switch attr {
+		case tv.Power:
+			state.Power = parseBool(v)
+		case tv.Volume:
+			state.Volume, _ = strconv.Atoi(v)
+		case tv.Mute:
+			state.Mute = parseBool(v)
+		case tv.Screen:
+			state.Screen = parseBool(v)
+		case tv.Input:
+			// Displays number their inputs in their own ways; we can only
+			// report the number.
+			state.Input.Connection = tv.Special
+			state.Input.Number, _ = strconv.Atoi(v)
+		}
+	}
+	return state, nil
+}
+
+func (g *genericTV) Capabilities() []tv.Capability {
+	return g.capabilities
+}
+
+func init() {
+	tv.RegisterModel("generic", &genericModel{})
+}
@@ -0,0 +1,160 @@
+package generic
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/DHowett/avantgarde/tv"
+)
+
+const testConfig = `
+transport: tcp
+terminator: "\r"
+ack: "^OK$"
+error: "^ERR"
+commands:
+  power:
+    set: "PWR {{if .Value}}1{{else}}0{{end}}\r"
+  volume:
+    set: "VOL {{printf \"%03d\" .Value}}\r"
+    increment: "VOL+\r"
+  input:
+    set: "INP {{.Value.Number}}\r"
+state:
+  power: {query: "PWR?\r", pattern: "^PWR ([01])$"}
+  volume: {query: "VOL?\r", pattern: "^VOL (\\d+)$"}
+`
+
+// fakeDisplay answers queries from a fixed table, acknowledges every other
+// well-formed command and records what it was sent.
+func fakeDisplay(t *testing.T, l net.Listener, got chan<- string) {
+	conn, err := l.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	replies := map[string]string{
+		"PWR?": "PWR 1",
+		"VOL?": "VOL 017",
+	}
+	br := bufio.NewReader(conn)
+	for {
+		line, err := br.ReadString('\r')
+		if err != nil {
+			return
+		}
+		line = strings.TrimSuffix(line, "\r")
+		reply, ok := replies[line]
+		if !ok {
+			got <- line
+			reply = "OK"
+			if strings.HasPrefix(line, "INP") {
+				reply = "ERR 3"
+			}
+		}
+		conn.Write([]byte(reply + "\r"))
+	}
+}
+
+func TestGeneric(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	got := make(chan string, 10)
+	go fakeDisplay(t, l, got)
+
+	model := &genericModel{}
+	cfg := model.NewConfig().(*Config)
+	if err := yaml.Unmarshal([]byte(testConfig), cfg); err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+	cfg.Address = l.Addr().String()
+
+	g, err := model.Initialize(nil, cfg)
+	if err != nil {
+		t.Fatalf("Failed to initialize: %v", err)
+	}
+
+	for op, expect := range map[*tv.Op]string{
+		{tv.Power, tv.Set, true}:        "PWR 1",
+		{tv.Volume, tv.Set, 7}:          "VOL 007",
+		{tv.Volume, tv.Increment, 1}:    "VOL+",
+		{tv.Raw, tv.Set, []byte("X\r")}: "X",
+	} {
+		if err := g.Do(op); err != nil {
+			t.Errorf("Failed to do %v: %v", op, err)
+		}
+		if line := <-got; line != expect {
+			t.Errorf("Got %q instead of %q for %v!", line, expect, op)
+		}
+	}
+
+	if err := g.Do(&tv.Op{tv.Input, tv.Set, tv.InputNumber{tv.HDMI, 3}}); err == nil {
+		t.Errorf("An error reply was not reported!")
+	}
+	<-got
+
+	if err := g.Do(&tv.Op{tv.Mute, tv.Set, true}); err == nil {
+		t.Errorf("An unconfigured command was not reported as unsupported!")
+	}
+
+	state, err := g.State()
+	if err != nil {
+		t.Fatalf("Failed to get state: %v", err)
+	}
+	if !state.Power || state.Volume != 17 {
+		t.Errorf("Got state %+v instead of power on at volume 17!", state)
+	}
+}
+
+func TestInvalidConfig(t *testing.T) {
+	model := &genericModel{}
+	for _, bad := range []string{
+		"commands: {warp: {set: \"W\\r\"}}",
+		"commands: {power: {engage: \"P\\r\"}}",
+		"commands: {power: {set: \"{{.Value\"}}",
+		"state: {power: {query: \"P?\\r\", pattern: \"P\"}}",
+		"ack: \"(\"",
+	} {
+		cfg := model.NewConfig().(*Config)
+		cfg.Address = "127.0.0.1:1"
+		if err := yaml.Unmarshal([]byte(bad), cfg); err != nil {
+			t.Fatalf("Failed to parse %q: %v", bad, err)
+		}
+		if _, err := model.Initialize(nil, cfg); err == nil {
+			t.Errorf("Accepted invalid config %q!", bad)
+		}
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	model := &genericModel{}
+	cfg := model.NewConfig().(*Config)
+	if err := yaml.Unmarshal([]byte(testConfig), cfg); err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+	cfg.Address = "127.0.0.1:1"
+
+	g, err := model.Initialize(nil, cfg)
+	if err != nil {
+		t.Fatalf("Failed to initialize: %v", err)
+	}
+
+	caps := g.(tv.Capable).Capabilities()
+	var got []string
+	for _, c := range caps {
+		got = append(got, fmt.Sprint(c.Attribute, c.Operators))
+	}
+	expect := "power [set] volume [set increment] input [set] raw [set]"
+	if strings.Join(got, " ") != expect {
+		t.Errorf("Got %q instead of %q!", strings.Join(got, " "), expect)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"regexp"
 	"strings"
 
 	"github.com/DHowett/avantgarde/tv"
@@ -42,6 +43,21 @@ var (
 	cmdSetLock             = cmdDigraph{'k', 'm'}
 )
 
+// tvInputToLG maps inputs to the codes the xb command takes.
+var tvInputToLG = map[tv.InputNumber]uint8{
+	{tv.Coaxial, 1}:   0x00, // digital antenna
+	{tv.Coaxial, 2}:   0x10, // analog antenna
+	{tv.Composite, 1}: 0x20,
+	{tv.Composite, 2}: 0x21,
+	{tv.Component, 1}: 0x40,
+	{tv.Component, 2}: 0x41,
+	{tv.PC, 1}:        0x60,
+	{tv.HDMI, 1}:      0x90,
+	{tv.HDMI, 2}:      0x91,
+	{tv.HDMI, 3}:      0x92,
+	{tv.HDMI, 4}:      0x93,
+}
+
 type Config struct {
 	SetID uint8
 }
@@ -58,6 +74,9 @@ func (l *lgModel) Initialize(rwc io.ReadWriteCloser, c tv.Config) (tv.TV, error)
 	if !ok {
 		return nil, fmt.Errorf("lg: invalid config type %T", c)
 	}
+	if rwc == nil {
+		return nil, errors.New("lg: no serial port configured")
+	}
 
 	lg := &lgTV{
 		config: lgc,
@@ -101,6 +120,10 @@ func (c *lgCommand) Serialize(SetID uint8) []byte {
 	return append(raw, 0x0D)
 }
 
+// rawCommandPattern matches a command, a set ID and one or more data
+// bytes, e.g. "ka 01 01".
+var rawCommandPattern = regexp.MustCompile(`^[a-z]{2} [0-9a-fA-F]{2}( [0-9a-fA-F]{2})+\r?$`)
+
 type lgTV struct {
 	config *Config
 	r      *bufio.Reader
@@ -155,7 +178,12 @@ func (lg *lgTV) Do(op *tv.Op) error {
 	case tv.OSD:
 		cmd = &lgCommand{cmdSetOSD, op.Value}
 	case tv.Input:
-		cmd = &lgCommand{cmdSetInput, uint8(op.Value.(int))}
+		i, ok := op.Value.(tv.InputNumber)
+		code, known := tvInputToLG[i]
+		if !ok || !known {
+			return fmt.Errorf("lg: no such input %v: %w", op.Value, tv.ErrInvalidValue)
+		}
+		cmd = &lgCommand{cmdSetInput, code}
 	case tv.Tuning:
 		cmd = lg.channelTuningCommand(op.Value.(tv.Tune))
 	case tv.Screen:
@@ -181,6 +209,9 @@ func (lg *lgTV) Do(op *tv.Op) error {
 		cmd = &lgCommand{cmdSetLock, op.Value}
 	case tv.Raw:
 		buf := op.Value.([]byte)
+		if !rawCommandPattern.Match(buf) {
+			return fmt.Errorf("lg: raw commands must look like \"ka 01 01\": %w", tv.ErrInvalidValue)
+		}
 		if buf[len(buf)-1] != 0x0D {
 			buf = append(buf, 0x0D)
 		}
@@ -189,7 +220,7 @@ func (lg *lgTV) Do(op *tv.Op) error {
 	}
 
 	if cmd == nil {
-		return errors.New("lg: unsupported")
+		return fmt.Errorf("lg: %w", tv.ErrUnsupported)
 	} else {
 		serialized := cmd.Serialize(lg.config.SetID)
 		lg.w.Write(serialized)
@@ -197,8 +228,8 @@ func (lg *lgTV) Do(op *tv.Op) error {
 	}
 }
 
-func (tv *lgTV) State() (*tv.State, error) {
-	return nil, errors.New("lg: unsupported")
+func (lg *lgTV) State() (*tv.State, error) {
+	return nil, fmt.Errorf("lg: %w", tv.ErrUnsupported)
 }
 
 func (lg *lgTV) run() {
@@ -230,6 +261,39 @@ func (lg *lgTV) run() {
 	}()
 }
 
+func inputs() []tv.InputNumber {
+	inputs := make([]tv.InputNumber, 0, len(tvInputToLG))
+	for k := range tvInputToLG {
+		inputs = append(inputs, k)
+	}
+	tv.SortInputs(inputs)
+	return inputs
+}
+
+var capabilities = []tv.Capability{
+	{Attribute: tv.Power, Operators: []tv.Operator{tv.Set}},
+	{Attribute: tv.Volume, Operators: []tv.Operator{tv.Set, tv.Increment, tv.Decrement}, Min: 0, Max: 100},
+	{Attribute: tv.Mute, Operators: []tv.Operator{tv.Set}},
+	{Attribute: tv.OSD, Operators: []tv.Operator{tv.Set}},
+	{Attribute: tv.Input, Operators: []tv.Operator{tv.Set}, Inputs: inputs()},
+	{Attribute: tv.Tuning, Operators: []tv.Operator{tv.Set}},
+	{Attribute: tv.Screen, Operators: []tv.Operator{tv.Set}},
+	{Attribute: tv.Contrast, Operators: []tv.Operator{tv.Set}, Min: 0, Max: 100},
+	{Attribute: tv.Brightness, Operators: []tv.Operator{tv.Set}, Min: 0, Max: 100},
+	{Attribute: tv.Color, Operators: []tv.Operator{tv.Set}, Min: 0, Max: 100},
+	{Attribute: tv.Tint, Operators: []tv.Operator{tv.Set}, Min: 0, Max: 100},
+	{Attribute: tv.Sharpness, Operators: []tv.Operator{tv.Set}, Min: 0, Max: 100},
+	{Attribute: tv.AudioBalance, Operators: []tv.Operator{tv.Set}, Min: 0, Max: 100},
+	{Attribute: tv.ColorTemperature, Operators: []tv.Operator{tv.Set}, Min: 0, Max: 100},
+	{Attribute: tv.Backlight, Operators: []tv.Operator{tv.Set}, Min: 0, Max: 100},
+	{Attribute: tv.Lock, Operators: []tv.Operator{tv.Set}},
+	{Attribute: tv.Raw, Operators: []tv.Operator{tv.Set}},
+}
+
+func (lg *lgTV) Capabilities() []tv.Capability {
+	return capabilities
+}
+
 func init() {
 	tv.RegisterModel("lg", &lgModel{})
 }
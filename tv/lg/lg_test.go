@@ -1,12 +1,16 @@
 package lg
 
+import "bufio"
 import "bytes"
+import "errors"
+import "net"
 import "testing"
 
+import "github.com/DHowett/avantgarde/tv"
+
 func TestSerialization(t *testing.T) {
 	lgc := &lgCommand{
-		'k',
-		'a',
+		cmdDigraph{'k', 'a'},
 		true,
 	}
 
@@ -16,8 +20,7 @@ func TestSerialization(t *testing.T) {
 	}
 
 	lgc = &lgCommand{
-		'm',
-		'a',
+		cmdDigraph{'m', 'a'},
 		struct {
 			A       uint8
 			Ch, Sub uint16
@@ -30,3 +33,30 @@ func TestSerialization(t *testing.T) {
 		t.Errorf("Got %x instead of %x for serializing %v!", lgc.Serialize(1), expect, lgc)
 	}
 }
+
+func TestInput(t *testing.T) {
+	// The pipe is left open: the TV's reader gives up on a closed port.
+	host, dev := net.Pipe()
+	lg, err := (&lgModel{}).Initialize(host, &Config{SetID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sent := make(chan string, 1)
+	go func() {
+		cmd, _ := bufio.NewReader(dev).ReadString('\r')
+		sent <- cmd
+	}()
+	if err := lg.Do(&tv.Op{tv.Input, tv.Set, tv.InputNumber{tv.HDMI, 2}}); err != nil {
+		t.Fatalf("Failed to switch inputs: %v", err)
+	}
+	if cmd := <-sent; cmd != "xb 01 91\r" {
+		t.Errorf("Got %q instead of switching to HDMI 2!", cmd)
+	}
+
+	for _, v := range []interface{}{tv.InputNumber{tv.HDMI, 9}, 2} {
+		if err := lg.Do(&tv.Op{tv.Input, tv.Set, v}); !errors.Is(err, tv.ErrInvalidValue) {
+			t.Errorf("Got %v instead of an invalid value error for %v!", err, v)
+		}
+	}
+}
@@ -0,0 +1,502 @@
+package lgwebos
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/DHowett/avantgarde/tv"
+)
+
+const requestTimeout = 10 * time.Second
+
+// pairingTimeout bounds how long the viewer has to accept the pairing prompt.
+var pairingTimeout = time.Minute
+
+type Config struct {
+	Address string
+	// MAC is used to wake the set, as webOS does not listen on the network
+	// while it is off.
+	MAC string
+	// KeyFile stores the client key issued when the set is first paired.
+	KeyFile string `yaml:"key_file"`
+}
+
+func (c Config) ModelSpecificRepresentation() interface{} {
+	return c
+}
+
+type webosModel struct{}
+
+func (l *webosModel) Initialize(rwc io.ReadWriteCloser, c tv.Config) (tv.TV, error) {
+	webosc, ok := c.(*Config)
+	if !ok {
+		return nil, fmt.Errorf("lg-webos: invalid config type %T", c)
+	}
+
+	if webosc.Address == "" {
+		return nil, errors.New("lg-webos: no address configured")
+	}
+	if webosc.KeyFile == "" {
+		webosc.KeyFile = "lg-webos-" + webosc.Address + ".key"
+	}
+
+	webos := newWebOSTV(webosc)
+	go webos.run()
+	return webos, nil
+}
+
+func (l *webosModel) NewConfig() tv.Config {
+	return &Config{}
+}
+
+func inputID(i tv.InputNumber) string {
+	switch i.Connection {
+	case tv.HDMI:
+		return fmt.Sprintf(inputHDMIFormat, i.Number)
+	case tv.Component:
+		return fmt.Sprintf(inputComponentFormat, i.Number)
+	case tv.Composite:
+		return fmt.Sprintf(inputCompositeFormat, i.Number)
+	}
+	return ""
+}
+
+func appInput(appID string) (tv.InputNumber, bool) {
+	number := func(prefix string) int {
+		n, err := strconv.Atoi(strings.TrimPrefix(appID, prefix))
+		if err != nil {
+			return 1
+		}
+		return n
+	}
+
+	switch {
+	case appID == appLiveTV:
+		return tv.InputNumber{tv.Coaxial, 1}, true
+	case strings.HasPrefix(appID, appHDMIPrefix):
+		return tv.InputNumber{tv.HDMI, number(appHDMIPrefix)}, true
+	case strings.HasPrefix(appID, appComponentPrefix):
+		return tv.InputNumber{tv.Component, number(appComponentPrefix)}, true
+	case strings.HasPrefix(appID, appCompositePrefix):
+		return tv.InputNumber{tv.Composite, number(appCompositePrefix)}, true
+	}
+	return tv.InputNumber{}, false
+}
+
+func channelNumber(t tv.Tune) string {
+	switch cht := t.C.(type) {
+	case tv.AnalogChannel:
+		return strconv.Itoa(int(cht))
+	case tv.DigitalChannel:
+		return fmt.Sprintf("%d-%d", cht.Ch, cht.Sub)
+	default:
+		return ""
+	}
+}
+
+func clamp(val int) int {
+	switch {
+	case val < 0:
+		return 0
+	case val > 100:
+		return 100
+	default:
+		return val
+	}
+}
+
+type webosTV struct {
+	config    *Config
+	clientKey string
+
+	mu      sync.Mutex
+	conn    *websocket.Conn
+	state   tv.State
+	nextID  int
+	pending map[string]chan *message
+
+	writeMu sync.Mutex
+}
+
+func newWebOSTV(config *Config) *webosTV {
+	webos := &webosTV{
+		config:  config,
+		pending: make(map[string]chan *message),
+	}
+	if key, err := ioutil.ReadFile(config.KeyFile); err == nil {
+		webos.clientKey = strings.TrimSpace(string(key))
+	}
+	return webos
+}
+
+func (webos *webosTV) url() string {
+	host := webos.config.Address
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, "3000")
+	}
+	return "ws://" + host + "/"
+}
+
+func (webos *webosTV) write(conn *websocket.Conn, msg *message) error {
+	webos.writeMu.Lock()
+	defer webos.writeMu.Unlock()
+	return conn.WriteJSON(msg)
+}
+
+// request sends a single SSAP request and waits for its reply.
+func (webos *webosTV) request(uri string, payload interface{}) (*message, error) {
+	msg := &message{Type: typeRequest, URI: uri}
+	if payload != nil {
+		raw, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		msg.Payload = raw
+	}
+
+	respCh := make(chan *message, 1)
+	webos.mu.Lock()
+	conn := webos.conn
+	if conn == nil {
+		webos.mu.Unlock()
+		return nil, fmt.Errorf("lg-webos: tv %w", tv.ErrDisconnected)
+	}
+	webos.nextID++
+	msg.ID = "req_" + strconv.Itoa(webos.nextID)
+	webos.pending[msg.ID] = respCh
+	webos.mu.Unlock()
+
+	if err := webos.write(conn, msg); err != nil {
+		webos.forget(msg.ID)
+		return nil, err
+	}
+
+	select {
+	case resp, ok := <-respCh:
+		if !ok {
+			return nil, fmt.Errorf("lg-webos: connection lost: %w", tv.ErrDisconnected)
+		}
+		if resp.Type == typeError {
+			return nil, fmt.Errorf("lg-webos: %s", resp.Error)
+		}
+		var rv returnValuePayload
+		if json.Unmarshal(resp.Payload, &rv) == nil && rv.ReturnValue != nil && !*rv.ReturnValue {
+			return nil, fmt.Errorf("lg-webos: %s failed: %s", uri, rv.ErrorText)
+		}
+		return resp, nil
+	case <-time.After(requestTimeout):
+		webos.forget(msg.ID)
+		return nil, fmt.Errorf("lg-webos: %s %w", uri, tv.ErrTimeout)
+	}
+}
+
+func (webos *webosTV) forget(id string) {
+	webos.mu.Lock()
+	delete(webos.pending, id)
+	webos.mu.Unlock()
+}
+
+// wake sends a Wake-on-LAN magic packet to the configured MAC address.
+func (webos *webosTV) wake() error {
+	if webos.config.MAC == "" {
+		return fmt.Errorf("lg-webos: cannot power on without a MAC address: %w", tv.ErrUnsupported)
+	}
+	mac, err := net.ParseMAC(webos.config.MAC)
+	if err != nil {
+		return err
+	}
+
+	packet := make([]byte, 6, 6+16*len(mac))
+	for i := range packet {
+		packet[i] = 0xFF
+	}
+	for i := 0; i < 16; i++ {
+		packet = append(packet, mac...)
+	}
+
+	conn, err := net.Dial("udp", "255.255.255.255:9")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write(packet)
+	return err
+}
+
+func (webos *webosTV) Do(op *tv.Op) error {
+	var uri string
+	var payload interface{}
+	switch op.Attribute {
+	case tv.Power:
+		if op.Value.(bool) {
+			return webos.wake()
+		}
+		uri = uriTurnOff
+	case tv.Volume:
+		switch op.Operator {
+		case tv.Set:
+			uri, payload = uriSetVolume, map[string]int{"volume": clamp(op.Value.(int))}
+		case tv.Increment:
+			uri = uriVolumeUp
+		case tv.Decrement:
+			uri = uriVolumeDown
+		}
+	case tv.Mute:
+		uri, payload = uriSetMute, map[string]bool{"mute": op.Value.(bool)}
+	case tv.Screen:
+		if op.Value.(bool) {
+			uri = uriTurnOnScreen
+		} else {
+			uri = uriTurnOffScreen
+		}
+	case tv.Input:
+		in := op.Value.(tv.InputNumber)
+		if in.Connection == tv.Coaxial {
+			uri, payload = uriLaunch, map[string]string{"id": appLiveTV}
+		} else if id := inputID(in); id != "" {
+			uri, payload = uriSwitchInput, map[string]string{"inputId": id}
+		}
+	case tv.Tuning:
+		if ch := channelNumber(op.Value.(tv.Tune)); ch != "" {
+			uri, payload = uriOpenChannel, map[string]string{"channelNumber": ch}
+		}
+	case tv.Raw:
+		_, err := webos.DoRaw(op.Value.([]byte))
+		return err
+	}
+
+	if uri == "" {
+		return fmt.Errorf("lg-webos: %w", tv.ErrUnsupported)
+	}
+	_, err := webos.request(uri, payload)
+	return err
+}
+
+// DoRaw sends a raw command, which is an SSAP URI optionally followed by a
+// space and a JSON payload, and returns the payload of the TV's response.
+func (webos *webosTV) DoRaw(cmd []byte) ([]byte, error) {
+	parts := strings.SplitN(string(cmd), " ", 2)
+	if !strings.HasPrefix(parts[0], "ssap://") {
+		return nil, fmt.Errorf("lg-webos: raw commands must be ssap:// URIs: %w", tv.ErrInvalidValue)
+	}
+	var payload interface{}
+	if len(parts) == 2 {
+		if !json.Valid([]byte(parts[1])) {
+			return nil, fmt.Errorf("lg-webos: raw command payload is not JSON: %w", tv.ErrInvalidValue)
+		}
+		payload = json.RawMessage(parts[1])
+	}
+	resp, err := webos.request(parts[0], payload)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Payload, nil
+}
+
+func (webos *webosTV) State() (*tv.State, error) {
+	webos.mu.Lock()
+	defer webos.mu.Unlock()
+	state := webos.state
+	return &state, nil
+}
+
+func (webos *webosTV) saveClientKey(key string) {
+	if key == "" || key == webos.clientKey {
+		return
+	}
+	webos.clientKey = key
+	err := ioutil.WriteFile(webos.config.KeyFile, []byte(key+"\n"), 0600)
+	if err != nil {
+		log.Printf("lg-webos: failed to save client key to %s: %v", webos.config.KeyFile, err)
+	}
+}
+
+// register pairs with the set, which prompts the viewer to accept the
+// connection unless we already hold a client key it issued.
+func (webos *webosTV) register(conn *websocket.Conn) error {
+	payload, err := json.Marshal(&registerPayload{
+		PairingType: pairingTypePrompt,
+		ClientKey:   webos.clientKey,
+		Manifest:    clientManifest,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = webos.write(conn, &message{Type: typeRegister, ID: idRegister, Payload: payload})
+	if err != nil {
+		return err
+	}
+
+	if err := conn.SetReadDeadline(time.Now().Add(pairingTimeout)); err != nil {
+		return err
+	}
+	for {
+		var msg message
+		if err := conn.ReadJSON(&msg); err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return fmt.Errorf("lg-webos: pairing %w", tv.ErrTimeout)
+			}
+			return err
+		}
+		if msg.ID != idRegister {
+			continue
+		}
+
+		switch msg.Type {
+		case typeRegistered:
+			var reg registeredPayload
+			if err := json.Unmarshal(msg.Payload, &reg); err != nil {
+				return err
+			}
+			webos.saveClientKey(reg.ClientKey)
+			return conn.SetReadDeadline(time.Time{})
+		case typeError:
+			return fmt.Errorf("lg-webos: pairing failed: %s", msg.Error)
+		}
+	}
+}
+
+func (webos *webosTV) subscribe(conn *websocket.Conn) error {
+	for id, uri := range map[string]string{
+		idSubVolume:        uriGetVolume,
+		idSubForegroundApp: uriGetForegroundApp,
+	} {
+		if err := webos.write(conn, &message{Type: typeSubscribe, ID: id, URI: uri}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (webos *webosTV) handleEvent(msg *message) {
+	webos.mu.Lock()
+	defer webos.mu.Unlock()
+
+	switch msg.ID {
+	case idSubVolume:
+		var vol volumePayload
+		if json.Unmarshal(msg.Payload, &vol) != nil {
+			return
+		}
+		if vol.VolumeStatus != nil {
+			webos.state.Volume = vol.VolumeStatus.Volume
+			webos.state.Mute = vol.VolumeStatus.MuteStatus
+		}
+		if vol.Volume != nil {
+			webos.state.Volume = *vol.Volume
+		}
+		if vol.Muted != nil {
+			webos.state.Mute = *vol.Muted
+		}
+	case idSubForegroundApp:
+		var app foregroundAppPayload
+		if json.Unmarshal(msg.Payload, &app) != nil {
+			return
+		}
+		if in, ok := appInput(app.AppID); ok {
+			webos.state.Input = in
+		}
+	}
+}
+
+func (webos *webosTV) session(conn *websocket.Conn) error {
+	if err := webos.register(conn); err != nil {
+		return err
+	}
+
+	webos.mu.Lock()
+	webos.conn = conn
+	webos.state.Power = true
+	webos.state.Screen = true
+	webos.mu.Unlock()
+
+	defer func() {
+		webos.mu.Lock()
+		webos.conn = nil
+		webos.state.Power = false
+		for id, ch := range webos.pending {
+			close(ch)
+			delete(webos.pending, id)
+		}
+		webos.mu.Unlock()
+	}()
+
+	if err := webos.subscribe(conn); err != nil {
+		return err
+	}
+
+	for {
+		var msg message
+		if err := conn.ReadJSON(&msg); err != nil {
+			return err
+		}
+
+		if msg.ID == idSubVolume || msg.ID == idSubForegroundApp {
+			webos.handleEvent(&msg)
+			continue
+		}
+
+		webos.mu.Lock()
+		ch, ok := webos.pending[msg.ID]
+		delete(webos.pending, msg.ID)
+		webos.mu.Unlock()
+		if ok {
+			ch <- &msg
+		}
+	}
+}
+
+func (webos *webosTV) run() {
+	dialer := &websocket.Dialer{HandshakeTimeout: 10 * time.Second}
+	for {
+		conn, _, err := dialer.Dial(webos.url(), nil)
+		if err != nil {
+			time.Sleep(5 * time.Second)
+			continue
+		}
+
+		err = webos.session(conn)
+		conn.Close()
+		if err != nil && !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+			log.Printf("lg-webos: %s: %v", webos.config.Address, err)
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+var capabilities = []tv.Capability{
+	{Attribute: tv.Power, Operators: []tv.Operator{tv.Set}},
+	{Attribute: tv.Volume, Operators: []tv.Operator{tv.Set, tv.Increment, tv.Decrement}, Min: 0, Max: 100},
+	{Attribute: tv.Mute, Operators: []tv.Operator{tv.Set}},
+	{Attribute: tv.Screen, Operators: []tv.Operator{tv.Set}},
+	{Attribute: tv.Input, Operators: []tv.Operator{tv.Set}},
+	{Attribute: tv.Tuning, Operators: []tv.Operator{tv.Set}},
+	{Attribute: tv.Raw, Operators: []tv.Operator{tv.Set}},
+}
+
+func (webos *webosTV) Capabilities() []tv.Capability {
+	return capabilities
+}
+
+func (webos *webosTV) Connected() bool {
+	webos.mu.Lock()
+	defer webos.mu.Unlock()
+	return webos.conn != nil
+}
+
+func init() {
+	tv.RegisterModel("lg-webos", &webosModel{})
+}
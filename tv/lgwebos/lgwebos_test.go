@@ -0,0 +1,170 @@
+package lgwebos
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/DHowett/avantgarde/tv"
+)
+
+// fakeWebOS pairs every client with a fixed key, answers subscriptions with
+// an initial event and acknowledges every request, reporting it on requests.
+type fakeWebOS struct {
+	requests chan *message
+	events   chan *message
+}
+
+func (f *fakeWebOS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	var writeMu sync.Mutex
+	write := func(msg *message) {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		conn.WriteJSON(msg)
+	}
+
+	go func() {
+		for ev := range f.events {
+			write(ev)
+		}
+	}()
+
+	for {
+		var msg message
+		if err := conn.ReadJSON(&msg); err != nil {
+			return
+		}
+
+		switch msg.Type {
+		case typeRegister:
+			write(&message{Type: typeResponse, ID: msg.ID, Payload: json.RawMessage(`{"pairingType":"PROMPT","returnValue":true}`)})
+			write(&message{Type: typeRegistered, ID: msg.ID, Payload: json.RawMessage(`{"client-key":"fake-key"}`)})
+		case typeSubscribe:
+			var payload string
+			switch msg.URI {
+			case uriGetVolume:
+				payload = `{"returnValue":true,"volume":12,"muted":false}`
+			case uriGetForegroundApp:
+				payload = `{"returnValue":true,"appId":"com.webos.app.hdmi2"}`
+			}
+			write(&message{Type: typeResponse, ID: msg.ID, Payload: json.RawMessage(payload)})
+		case typeRequest:
+			f.requests <- &msg
+			write(&message{Type: typeResponse, ID: msg.ID, Payload: json.RawMessage(`{"returnValue":true}`)})
+		}
+	}
+}
+
+func waitForState(t *testing.T, webos tv.TV, cond func(*tv.State) bool) *tv.State {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		state, err := webos.State()
+		if err != nil {
+			t.Fatalf("Failed to get state: %v", err)
+		}
+		if cond(state) {
+			return state
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for state; last was %+v", state)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSession(t *testing.T) {
+	fake := &fakeWebOS{
+		requests: make(chan *message, 10),
+		events:   make(chan *message, 10),
+	}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	defer close(fake.events)
+
+	keyFile := filepath.Join(t.TempDir(), "webos.key")
+	webos, err := (&webosModel{}).Initialize(nil, &Config{
+		Address: strings.TrimPrefix(srv.URL, "http://"),
+		KeyFile: keyFile,
+	})
+	if err != nil {
+		t.Fatalf("Failed to initialize: %v", err)
+	}
+
+	state := waitForState(t, webos, func(s *tv.State) bool {
+		return s.Power && s.Volume == 12 && s.Input == tv.InputNumber{tv.HDMI, 2}
+	})
+	if state.Mute {
+		t.Errorf("Got muted instead of unmuted from the volume subscription!")
+	}
+
+	key, err := ioutil.ReadFile(keyFile)
+	if err != nil || strings.TrimSpace(string(key)) != "fake-key" {
+		t.Errorf("Got client key %q (%v) instead of fake-key!", key, err)
+	}
+
+	if err := webos.Do(&tv.Op{tv.Volume, tv.Set, 15}); err != nil {
+		t.Fatalf("Failed to set volume: %v", err)
+	}
+	req := <-fake.requests
+	if req.URI != uriSetVolume || string(req.Payload) != `{"volume":15}` {
+		t.Errorf("Got %s %s instead of %s {\"volume\":15}!", req.URI, req.Payload, uriSetVolume)
+	}
+
+	if err := webos.Do(&tv.Op{tv.Input, tv.Set, tv.InputNumber{tv.HDMI, 3}}); err != nil {
+		t.Fatalf("Failed to switch input: %v", err)
+	}
+	req = <-fake.requests
+	if req.URI != uriSwitchInput || string(req.Payload) != `{"inputId":"HDMI_3"}` {
+		t.Errorf("Got %s %s instead of %s {\"inputId\":\"HDMI_3\"}!", req.URI, req.Payload, uriSwitchInput)
+	}
+
+	fake.events <- &message{Type: typeResponse, ID: idSubVolume, Payload: json.RawMessage(`{"volumeStatus":{"volume":20,"muteStatus":true}}`)}
+	waitForState(t, webos, func(s *tv.State) bool {
+		return s.Volume == 20 && s.Mute
+	})
+}
+
+func TestRegisterTimeout(t *testing.T) {
+	defer func(d time.Duration) { pairingTimeout = d }(pairingTimeout)
+	pairingTimeout = 50 * time.Millisecond
+
+	// The viewer never answers the prompt.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	if err := newWebOSTV(&Config{}).register(conn); !errors.Is(err, tv.ErrTimeout) {
+		t.Errorf("Got %v instead of a timeout!", err)
+	}
+}
@@ -0,0 +1,116 @@
+package lgwebos
+
+import (
+	"encoding/json"
+)
+
+const (
+	typeRegister   = `register`
+	typeRegistered = `registered`
+	typeRequest    = `request`
+	typeSubscribe  = `subscribe`
+	typeResponse   = `response`
+	typeError      = `error`
+)
+
+const (
+	uriTurnOff          = `ssap://system/turnOff`
+	uriTurnOffScreen    = `ssap://com.webos.service.tvpower/power/turnOffScreen`
+	uriTurnOnScreen     = `ssap://com.webos.service.tvpower/power/turnOnScreen`
+	uriGetVolume        = `ssap://audio/getVolume`
+	uriSetVolume        = `ssap://audio/setVolume`
+	uriVolumeUp         = `ssap://audio/volumeUp`
+	uriVolumeDown       = `ssap://audio/volumeDown`
+	uriSetMute          = `ssap://audio/setMute`
+	uriSwitchInput      = `ssap://tv/switchInput`
+	uriOpenChannel      = `ssap://tv/openChannel`
+	uriLaunch           = `ssap://system.launcher/launch`
+	uriGetForegroundApp = `ssap://com.webos.applicationManager/getForegroundAppInfo`
+)
+
+// Subscriptions are keyed by fixed message IDs so that the events they
+// produce can be told apart from replies to ordinary requests.
+const (
+	idRegister         = `register_0`
+	idSubVolume        = `sub_volume`
+	idSubForegroundApp = `sub_foreground_app`
+)
+
+const pairingTypePrompt = `PROMPT`
+
+const (
+	appLiveTV          = `com.webos.app.livetv`
+	appHDMIPrefix      = `com.webos.app.hdmi`
+	appComponentPrefix = `com.webos.app.externalinput.component`
+	appCompositePrefix = `com.webos.app.externalinput.av`
+)
+
+const (
+	inputHDMIFormat      = `HDMI_%d`
+	inputComponentFormat = `COMP_%d`
+	inputCompositeFormat = `AV_%d`
+)
+
+type message struct {
+	Type    string          `json:"type"`
+	ID      string          `json:"id,omitempty"`
+	URI     string          `json:"uri,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+type registerPayload struct {
+	ForcePairing bool     `json:"forcePairing"`
+	PairingType  string   `json:"pairingType"`
+	ClientKey    string   `json:"client-key,omitempty"`
+	Manifest     manifest `json:"manifest"`
+}
+
+type manifest struct {
+	ManifestVersion int      `json:"manifestVersion"`
+	AppVersion      string   `json:"appVersion"`
+	Permissions     []string `json:"permissions"`
+}
+
+var clientManifest = manifest{
+	ManifestVersion: 1,
+	AppVersion:      "1.1",
+	Permissions: []string{
+		"CONTROL_AUDIO",
+		"CONTROL_DISPLAY",
+		"CONTROL_INPUT_TV",
+		"CONTROL_POWER",
+		"CONTROL_TV_SCREEN",
+		"READ_CURRENT_CHANNEL",
+		"READ_INPUT_DEVICE_LIST",
+		"READ_RUNNING_APPS",
+		"READ_TV_CURRENT_TIME",
+		"READ_INSTALLED_APPS",
+		"TEST_SECURE",
+		"WRITE_NOTIFICATION_TOAST",
+	},
+}
+
+type registeredPayload struct {
+	ClientKey string `json:"client-key"`
+}
+
+type returnValuePayload struct {
+	ReturnValue *bool  `json:"returnValue"`
+	ErrorText   string `json:"errorText"`
+}
+
+// Older firmware reports volume and mute at the top level; newer firmware
+// nests them under volumeStatus.
+type volumePayload struct {
+	Volume       *int  `json:"volume"`
+	Muted        *bool `json:"muted"`
+	VolumeStatus *struct {
+		Volume     int  `json:"volume"`
+		MuteStatus bool `json:"muteStatus"`
+	} `json:"volumeStatus"`
+}
+
+type foregroundAppPayload struct {
+	AppID string `json:"appId"`
+}
@@ -0,0 +1,287 @@
+package matrix
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/DHowett/avantgarde/tv"
+)
+
+// dialect describes one of the ASCII routing protocols spoken by common
+// HDMI matrices.
+type dialect struct {
+	route func(input, output int) string
+	// query asks which input an output shows; the switch answers as it
+	// confirms a route.
+	query func(output int) string
+	// routed matches the switch's confirmation of a route, capturing the
+	// input and output numbers.
+	routed *regexp.Regexp
+}
+
+var dialects = map[string]dialect{
+	// "1V3." routes input 1 to output 3.
+	"dot": {
+		route: func(input, output int) string {
+			return fmt.Sprintf("%dV%d.", input, output)
+		},
+		// "Status3." asks what output 3 shows.
+		query: func(output int) string {
+			return fmt.Sprintf("Status%d.", output)
+		},
+		routed: regexp.MustCompile(`(\d+)V(\d+)\.`),
+	},
+	// "SET SW in1 out3" routes input 1 to output 3.
+	"set": {
+		route: func(input, output int) string {
+			return fmt.Sprintf("SET SW in%d out%d\r\n", input, output)
+		},
+		// "GET SW out3" asks what output 3 shows.
+		query: func(output int) string {
+			return fmt.Sprintf("GET SW out%d\r\n", output)
+		},
+		routed: regexp.MustCompile(`(?i)SW in(\d+) out(\d+)`),
+	},
+}
+
+type Config struct {
+	// Address is the switch's host:port; matrices do not agree on a port.
+	Address  string
+	Protocol string
+	Inputs   int
+	Outputs  int
+}
+
+func (c Config) ModelSpecificRepresentation() interface{} {
+	return c
+}
+
+type matrixModel struct{}
+
+func (l *matrixModel) Initialize(rwc io.ReadWriteCloser, c tv.Config) (tv.TV, error) {
+	matrixc, ok := c.(*Config)
+	if !ok {
+		return nil, fmt.Errorf("matrix: invalid config type %T", c)
+	}
+
+	d, ok := dialects[matrixc.Protocol]
+	if !ok {
+		return nil, fmt.Errorf("matrix: unknown protocol %q", matrixc.Protocol)
+	}
+	if rwc == nil && matrixc.Address == "" {
+		return nil, errors.New("matrix: neither a serial port nor an address was configured")
+	}
+
+	m := &matrixSwitch{
+		config:  matrixc,
+		dialect: d,
+		serial:  rwc,
+		routes:  make(map[int]int),
+		changed: make(chan struct{}),
+		timeout: 2 * time.Second,
+	}
+	go m.run()
+	return m, nil
+}
+
+func (l *matrixModel) NewConfig() tv.Config {
+	return &Config{Protocol: "dot", Inputs: 4, Outputs: 8}
+}
+
+type matrixSwitch struct {
+	config  *Config
+	dialect dialect
+	serial  io.ReadWriteCloser
+
+	mu     sync.Mutex
+	w      io.Writer
+	routes map[int]int
+	// changed is closed, and replaced, whenever routes is updated.
+	changed chan struct{}
+	// timeout bounds how long Routes waits for the switch to answer.
+	timeout time.Duration
+}
+
+func (m *matrixSwitch) send(cmd string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.w == nil {
+		return fmt.Errorf("matrix: switch %w", tv.ErrDisconnected)
+	}
+	_, err := io.WriteString(m.w, cmd)
+	return err
+}
+
+func (m *matrixSwitch) Route(input, output int) error {
+	if input < 1 || input > m.config.Inputs {
+		return fmt.Errorf("matrix: input %d out of range: %w", input, tv.ErrInvalidValue)
+	}
+	if output < 1 || output > m.config.Outputs {
+		return fmt.Errorf("matrix: output %d out of range: %w", output, tv.ErrInvalidValue)
+	}
+
+	if err := m.send(m.dialect.route(input, output)); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.routes[output] = input
+	m.mu.Unlock()
+	return nil
+}
+
+// unknown lists the outputs whose input we have not yet heard of.
+func (m *matrixSwitch) unknown() []int {
+	var outputs []int
+	for o := 1; o <= m.config.Outputs; o++ {
+		if _, ok := m.routes[o]; !ok {
+			outputs = append(outputs, o)
+		}
+	}
+	return outputs
+}
+
+// query asks the switch which input each output shows. The answers arrive
+// through parseLine.
+func (m *matrixSwitch) query(outputs []int) error {
+	for _, o := range outputs {
+		if err := m.send(m.dialect.query(o)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Routes returns the crosspoint table, first asking the switch about any
+// output we have not heard of, as is every output after a restart.
+func (m *matrixSwitch) Routes() (map[int]int, error) {
+	m.mu.Lock()
+	unknown := m.unknown()
+	m.mu.Unlock()
+	if len(unknown) > 0 {
+		if err := m.query(unknown); err != nil {
+			return nil, err
+		}
+		deadline := time.After(m.timeout)
+		m.mu.Lock()
+		for len(m.unknown()) > 0 {
+			changed := m.changed
+			m.mu.Unlock()
+			select {
+			case <-changed:
+			case <-deadline:
+				return nil, fmt.Errorf("matrix: switch did not report every route: %w", tv.ErrTimeout)
+			}
+			m.mu.Lock()
+		}
+		m.mu.Unlock()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	routes := make(map[int]int, len(m.routes))
+	for o, i := range m.routes {
+		routes[o] = i
+	}
+	return routes, nil
+}
+
+// parseLine records any route the switch reports, whether in reply to one
+// of our commands or to a press of its front panel.
+func (m *matrixSwitch) parseLine(line string) {
+	for _, match := range m.dialect.routed.FindAllStringSubmatch(line, -1) {
+		input, _ := strconv.Atoi(match[1])
+		output, _ := strconv.Atoi(match[2])
+		m.mu.Lock()
+		m.routes[output] = input
+		close(m.changed)
+		m.changed = make(chan struct{})
+		m.mu.Unlock()
+	}
+}
+
+func (m *matrixSwitch) Do(op *tv.Op) error {
+	switch op.Attribute {
+	case tv.Raw:
+		buf := op.Value.([]byte)
+		if len(buf) == 0 {
+			return fmt.Errorf("matrix: empty raw command: %w", tv.ErrInvalidValue)
+		}
+		return m.send(string(buf))
+	}
+	return fmt.Errorf("matrix: %w", tv.ErrUnsupported)
+}
+
+func (m *matrixSwitch) State() (*tv.State, error) {
+	return nil, fmt.Errorf("matrix: %w", tv.ErrUnsupported)
+}
+
+func (m *matrixSwitch) connect() (io.ReadWriteCloser, error) {
+	if m.serial != nil {
+		return m.serial, nil
+	}
+	return net.DialTimeout("tcp", m.config.Address, 10*time.Second)
+}
+
+func (m *matrixSwitch) run() {
+	for {
+		rwc, err := m.connect()
+		if err != nil {
+			time.Sleep(5 * time.Second)
+			continue
+		}
+
+		m.mu.Lock()
+		m.w = rwc
+		m.mu.Unlock()
+		// Learn the crosspoint table, which the switch will not otherwise
+		// tell us until something changes.
+		go m.query(allOutputs(m.config.Outputs))
+
+		s := bufio.NewScanner(rwc)
+		for s.Scan() {
+			m.parseLine(s.Text())
+		}
+
+		m.mu.Lock()
+		m.w = nil
+		m.mu.Unlock()
+		if m.serial == nil {
+			rwc.Close()
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+func allOutputs(n int) []int {
+	outputs := make([]int, n)
+	for i := range outputs {
+		outputs[i] = i + 1
+	}
+	return outputs
+}
+
+var capabilities = []tv.Capability{
+	{Attribute: tv.Raw, Operators: []tv.Operator{tv.Set}},
+}
+
+func (m *matrixSwitch) Capabilities() []tv.Capability {
+	return capabilities
+}
+
+func (m *matrixSwitch) Connected() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.w != nil
+}
+
+func init() {
+	tv.RegisterModel("matrix", &matrixModel{})
+}
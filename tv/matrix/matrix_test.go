@@ -0,0 +1,100 @@
+package matrix
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/DHowett/avantgarde/tv"
+)
+
+func TestDialects(t *testing.T) {
+	if cmd := dialects["dot"].route(1, 3); cmd != "1V3." {
+		t.Errorf("Got %q instead of %q for routing 1 to 3!", cmd, "1V3.")
+	}
+	if cmd := dialects["set"].route(1, 3); cmd != "SET SW in1 out3\r\n" {
+		t.Errorf("Got %q instead of %q for routing 1 to 3!", cmd, "SET SW in1 out3\r\n")
+	}
+	if cmd := dialects["set"].query(3); cmd != "GET SW out3\r\n" {
+		t.Errorf("Got %q instead of %q for asking about output 3!", cmd, "GET SW out3\r\n")
+	}
+}
+
+func TestParseLine(t *testing.T) {
+	m := &matrixSwitch{config: &Config{Outputs: 3}, dialect: dialects["set"], routes: make(map[int]int), changed: make(chan struct{})}
+	m.parseLine("sw in2 out1")
+	m.parseLine("SW in4 out3")
+	m.parseLine("SW in1 out2")
+	m.parseLine("garbage")
+
+	routes, _ := m.Routes()
+	expect := map[int]int{1: 2, 2: 1, 3: 4}
+	if !reflect.DeepEqual(routes, expect) {
+		t.Errorf("Got routes %v instead of %v!", routes, expect)
+	}
+}
+
+// fakeSwitch answers "dot" status queries from its crosspoint table, as a
+// switch that was set up before avantgarde started.
+func fakeSwitch(t *testing.T, routes map[int]int) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	status := regexp.MustCompile(`Status(\d+)\.`)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			cmd, err := r.ReadString('.')
+			if err != nil {
+				return
+			}
+			if m := status.FindStringSubmatch(cmd); m != nil {
+				o, _ := strconv.Atoi(m[1])
+				fmt.Fprintf(conn, "%dV%d.\r\n", routes[o], o)
+			}
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestRoutesQueried(t *testing.T) {
+	expect := map[int]int{1: 2, 2: 2, 3: 1, 4: 4}
+	addr := fakeSwitch(t, expect)
+
+	sw, err := (&matrixModel{}).Initialize(nil, &Config{Address: addr, Protocol: "dot", Inputs: 4, Outputs: 4})
+	if err != nil {
+		t.Fatalf("Failed to initialize: %v", err)
+	}
+	m := sw.(*matrixSwitch)
+	deadline := time.Now().Add(time.Second)
+	for !m.Connected() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	routes, err := m.Routes()
+	if err != nil || !reflect.DeepEqual(routes, expect) {
+		t.Errorf("Got routes %v, %v instead of %v from the switch!", routes, err, expect)
+	}
+}
+
+func TestRoutesTimeout(t *testing.T) {
+	m := &matrixSwitch{config: &Config{Outputs: 2}, dialect: dialects["dot"], routes: map[int]int{1: 1}, changed: make(chan struct{}), timeout: 10 * time.Millisecond}
+	m.w = io.Discard
+	if _, err := m.Routes(); !errors.Is(err, tv.ErrTimeout) {
+		t.Errorf("Got %v instead of a timeout from a switch that never answered!", err)
+	}
+}
@@ -0,0 +1,308 @@
+package onkyo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/DHowett/avantgarde/tv"
+)
+
+type Config struct {
+	Address string
+	// Zone selects which of the receiver's zones this instance controls.
+	Zone int
+}
+
+func (c Config) ModelSpecificRepresentation() interface{} {
+	return c
+}
+
+type eiscpModel struct{}
+
+func (l *eiscpModel) Initialize(rwc io.ReadWriteCloser, c tv.Config) (tv.TV, error) {
+	eiscpc, ok := c.(*Config)
+	if !ok {
+		return nil, fmt.Errorf("onkyo: invalid config type %T", c)
+	}
+
+	if eiscpc.Address == "" {
+		return nil, errors.New("onkyo: no address configured")
+	}
+	zone, ok := zones[eiscpc.Zone]
+	if !ok {
+		return nil, fmt.Errorf("onkyo: unsupported zone %d", eiscpc.Zone)
+	}
+
+	onkyo := newOnkyoAVR(eiscpc, zone)
+	go onkyo.run()
+	return onkyo, nil
+}
+
+func (l *eiscpModel) NewConfig() tv.Config {
+	return &Config{Zone: 1}
+}
+
+var tvInputToOnkyo = map[tv.InputNumber]string{
+	{tv.HDMI, 1}:      "10", // BD/DVD
+	{tv.HDMI, 2}:      "01", // CBL/SAT
+	{tv.HDMI, 3}:      "02", // GAME
+	{tv.HDMI, 4}:      "05", // PC
+	{tv.HDMI, 5}:      "00", // STB/DVR
+	{tv.Composite, 1}: "03", // AUX
+	{tv.Coaxial, 1}:   "26", // TUNER
+	{tv.Special, 1}:   "2B", // NET
+}
+
+var onkyoInputToTV = map[string]tv.InputNumber{}
+
+func clamp(val int) int {
+	switch {
+	case val < 0:
+		return 0
+	case val > 100:
+		return 100
+	default:
+		return val
+	}
+}
+
+type onkyoAVR struct {
+	config *Config
+	zone   zoneCommands
+
+	mu    sync.Mutex
+	conn  net.Conn
+	state tv.State
+
+	eventCh       chan *tv.Op
+	eventHandlers map[tv.Attribute][]func(*tv.Op)
+}
+
+func newOnkyoAVR(config *Config, zone zoneCommands) *onkyoAVR {
+	onkyo := &onkyoAVR{
+		config:        config,
+		zone:          zone,
+		eventCh:       make(chan *tv.Op, 1000),
+		eventHandlers: make(map[tv.Attribute][]func(*tv.Op)),
+	}
+	onkyo.init()
+	return onkyo
+}
+
+func (onkyo *onkyoAVR) when(ev tv.Attribute, handler func(*tv.Op)) {
+	onkyo.eventHandlers[ev] = append(onkyo.eventHandlers[ev], handler)
+}
+
+func (onkyo *onkyoAVR) send(command, parameter string) error {
+	onkyo.mu.Lock()
+	defer onkyo.mu.Unlock()
+	if onkyo.conn == nil {
+		return fmt.Errorf("onkyo: receiver %w", tv.ErrDisconnected)
+	}
+	_, err := onkyo.conn.Write((&iscpMessage{command, parameter}).Serialize())
+	return err
+}
+
+// parseMessage folds a status message into the receiver's state and returns
+// the event it represents. Messages for other zones are ignored.
+func (onkyo *onkyoAVR) parseMessage(msg *iscpMessage) *tv.Op {
+	op := &tv.Op{Operator: tv.Set}
+
+	onkyo.mu.Lock()
+	defer onkyo.mu.Unlock()
+
+	switch msg.command {
+	case onkyo.zone.power:
+		op.Attribute = tv.Power
+		op.Value = msg.parameter == paramOn
+		onkyo.state.Power = msg.parameter == paramOn
+	case onkyo.zone.volume:
+		vol, err := strconv.ParseUint(msg.parameter, 16, 8)
+		if err != nil {
+			return nil
+		}
+		op.Attribute = tv.Volume
+		op.Value = int(vol)
+		onkyo.state.Volume = int(vol)
+	case onkyo.zone.mute:
+		op.Attribute = tv.Mute
+		op.Value = msg.parameter == paramOn
+		onkyo.state.Mute = msg.parameter == paramOn
+	case onkyo.zone.input:
+		in, ok := onkyoInputToTV[msg.parameter]
+		if !ok {
+			in = tv.InputNumber{tv.Special, 0}
+		}
+		op.Attribute = tv.Input
+		op.Value = in
+		onkyo.state.Input = in
+	default:
+		return nil
+	}
+	return op
+}
+
+func (onkyo *onkyoAVR) Do(op *tv.Op) error {
+	var cmd, param string
+	switch op.Attribute {
+	case tv.Power:
+		cmd, param = onkyo.zone.power, paramOff
+		if op.Value.(bool) {
+			param = paramOn
+		}
+	case tv.Volume:
+		cmd = onkyo.zone.volume
+		switch op.Operator {
+		case tv.Set:
+			param = fmt.Sprintf("%02X", clamp(op.Value.(int)))
+		case tv.Increment:
+			param = paramUp
+		case tv.Decrement:
+			param = paramDown
+		}
+	case tv.Mute:
+		cmd = onkyo.zone.mute
+		switch op.Operator {
+		case tv.Set:
+			param = paramOff
+			if op.Value.(bool) {
+				param = paramOn
+			}
+		case tv.Toggle:
+			param = paramToggle
+		}
+	case tv.Input:
+		if source, ok := tvInputToOnkyo[op.Value.(tv.InputNumber)]; ok {
+			cmd, param = onkyo.zone.input, source
+		}
+	case tv.Raw:
+		// Raw commands are bare ISCP messages, e.g. "LMD0C".
+		raw := string(op.Value.([]byte))
+		if !validRaw(raw) {
+			return fmt.Errorf("onkyo: raw commands must be three capital letters and up to %d printable characters: %w", maxRawParameter, tv.ErrInvalidValue)
+		}
+		return onkyo.send(raw[:3], raw[3:])
+	}
+
+	if cmd == "" || param == "" {
+		return fmt.Errorf("onkyo: %w", tv.ErrUnsupported)
+	}
+	return onkyo.send(cmd, param)
+}
+
+// maxRawParameter bounds the parameter of a raw command; no real one comes
+// close.
+const maxRawParameter = 64
+
+func validRaw(raw string) bool {
+	if len(raw) < 3 || len(raw) > 3+maxRawParameter {
+		return false
+	}
+	for i, c := range raw {
+		if i < 3 && (c < 'A' || c > 'Z') || c < 0x20 || c > 0x7E {
+			return false
+		}
+	}
+	return true
+}
+
+func (onkyo *onkyoAVR) State() (*tv.State, error) {
+	onkyo.mu.Lock()
+	defer onkyo.mu.Unlock()
+	state := onkyo.state
+	return &state, nil
+}
+
+func (onkyo *onkyoAVR) init() {
+	onkyo.when(tv.Power, func(op *tv.Op) {
+		if pval, ok := op.Value.(bool); ok && pval {
+			go func() {
+				onkyo.send(onkyo.zone.volume, paramQuery)
+				onkyo.send(onkyo.zone.mute, paramQuery)
+				onkyo.send(onkyo.zone.input, paramQuery)
+			}()
+		}
+	})
+}
+
+func (onkyo *onkyoAVR) run() {
+	go func() {
+		for event := range onkyo.eventCh {
+			for _, handler := range onkyo.eventHandlers[event.Attribute] {
+				handler(event)
+			}
+		}
+	}()
+
+	addr := onkyo.config.Address
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "60128")
+	}
+	for {
+		conn, err := net.DialTimeout("tcp", addr, 10*time.Second)
+		if err != nil {
+			time.Sleep(5 * time.Second)
+			continue
+		}
+
+		onkyo.mu.Lock()
+		onkyo.conn = conn
+		onkyo.mu.Unlock()
+
+		onkyo.send(onkyo.zone.power, paramQuery)
+		for {
+			msg, err := readMessage(conn)
+			if err != nil {
+				break
+			}
+			if event := onkyo.parseMessage(msg); event != nil {
+				onkyo.eventCh <- event
+			}
+		}
+
+		onkyo.mu.Lock()
+		onkyo.conn = nil
+		onkyo.mu.Unlock()
+		conn.Close()
+		time.Sleep(time.Second)
+	}
+}
+
+func inputs() []tv.InputNumber {
+	inputs := make([]tv.InputNumber, 0, len(tvInputToOnkyo))
+	for k := range tvInputToOnkyo {
+		inputs = append(inputs, k)
+	}
+	tv.SortInputs(inputs)
+	return inputs
+}
+
+var capabilities = []tv.Capability{
+	{Attribute: tv.Power, Operators: []tv.Operator{tv.Set}},
+	{Attribute: tv.Volume, Operators: []tv.Operator{tv.Set, tv.Increment, tv.Decrement}, Min: 0, Max: 100},
+	{Attribute: tv.Mute, Operators: []tv.Operator{tv.Set, tv.Toggle}},
+	{Attribute: tv.Input, Operators: []tv.Operator{tv.Set}, Inputs: inputs()},
+	{Attribute: tv.Raw, Operators: []tv.Operator{tv.Set}},
+}
+
+func (onkyo *onkyoAVR) Capabilities() []tv.Capability {
+	return capabilities
+}
+
+func (onkyo *onkyoAVR) Connected() bool {
+	onkyo.mu.Lock()
+	defer onkyo.mu.Unlock()
+	return onkyo.conn != nil
+}
+
+func init() {
+	for k, v := range tvInputToOnkyo {
+		onkyoInputToTV[v] = k
+	}
+	tv.RegisterModel("onkyo", &eiscpModel{})
+}
@@ -0,0 +1,74 @@
+package onkyo
+
+import "bytes"
+import "net"
+import "testing"
+
+import "github.com/DHowett/avantgarde/tv"
+
+func TestSerialization(t *testing.T) {
+	m := &iscpMessage{"PWR", paramOn}
+
+	expect := []byte("ISCP\x00\x00\x00\x10\x00\x00\x00\x08\x01\x00\x00\x00!1PWR01\r")
+	if !bytes.Equal(m.Serialize(), expect) {
+		t.Errorf("Got %q instead of %q for serializing %v!", m.Serialize(), expect, m)
+	}
+}
+
+func TestDeserialization(t *testing.T) {
+	raw := []byte("ISCP\x00\x00\x00\x10\x00\x00\x00\x0a\x01\x00\x00\x00!1MVL28\x1a\r\n")
+	m, err := readMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Failed to parse %q: %v", raw, err)
+	}
+	if m.command != "MVL" || m.parameter != "28" {
+		t.Errorf("Got %v instead of MVL 28 for %q!", m, raw)
+	}
+
+	raw = []byte("ISCQ\x00\x00\x00\x10\x00\x00\x00\x0a\x01\x00\x00\x00!1MVL28\x1a\r\n")
+	if _, err = readMessage(bytes.NewReader(raw)); err != errBadFrame {
+		t.Errorf("Got %v instead of a framing error for %q!", err, raw)
+	}
+}
+
+func TestZoneEvents(t *testing.T) {
+	onkyo := newOnkyoAVR(&Config{Zone: 2}, zones[2])
+
+	if ev := onkyo.parseMessage(&iscpMessage{"MVL", "28"}); ev != nil {
+		t.Errorf("Got event %v for a main zone message in zone 2!", ev)
+	}
+
+	ev := onkyo.parseMessage(&iscpMessage{"ZVL", "28"})
+	if ev == nil || ev.Attribute != tv.Volume || ev.Value != 0x28 {
+		t.Errorf("Got event %v instead of volume 40!", ev)
+	}
+
+	onkyo.parseMessage(&iscpMessage{"SLZ", "01"})
+	if onkyo.state.Volume != 0x28 || onkyo.state.Input != (tv.InputNumber{tv.HDMI, 2}) {
+		t.Errorf("Got state %+v instead of volume 40 on HDMI 2!", onkyo.state)
+	}
+}
+
+func TestAddress(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// The address carries its own port, which is used instead of 60128.
+	if _, err := (&eiscpModel{}).Initialize(nil, &Config{Address: l.Addr().String(), Zone: 1}); err != nil {
+		t.Fatal(err)
+	}
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// The receiver is asked whether it is on as soon as it is connected.
+	m, err := readMessage(conn)
+	if err != nil || m.command != "PWR" || m.parameter != paramQuery {
+		t.Errorf("Got %v, %v instead of a power query!", m, err)
+	}
+}
@@ -0,0 +1,98 @@
+package onkyo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+)
+
+const (
+	paramOn     = `01`
+	paramOff    = `00`
+	paramToggle = `TG`
+	paramUp     = `UP`
+	paramDown   = `DOWN`
+	paramQuery  = `QSTN`
+)
+
+// zoneCommands holds the ISCP command names for a single zone; the main
+// zone and zone 2 use different commands for the same controls.
+type zoneCommands struct {
+	power, volume, mute, input string
+}
+
+var zones = map[int]zoneCommands{
+	1: {`PWR`, `MVL`, `AMT`, `SLI`},
+	2: {`ZPW`, `ZVL`, `ZMT`, `SLZ`},
+}
+
+const (
+	eiscpMagic      = `ISCP`
+	eiscpHeaderSize = 16
+	eiscpVersion    = 0x01
+	iscpStart       = '!'
+	iscpUnitType    = '1' // receiver
+)
+
+type eiscpHeader struct {
+	Magic      [4]byte
+	HeaderSize uint32
+	DataSize   uint32
+	Version    uint8
+	Reserved   [3]byte
+}
+
+// iscpMessage is a single ISCP message, such as PWR01, without the start
+// character, unit type or terminator.
+type iscpMessage struct {
+	command   string
+	parameter string
+}
+
+func (m *iscpMessage) Serialize() []byte {
+	data := []byte{iscpStart, iscpUnitType}
+	data = append(data, m.command...)
+	data = append(data, m.parameter...)
+	data = append(data, '\r')
+
+	hdr := eiscpHeader{
+		HeaderSize: eiscpHeaderSize,
+		DataSize:   uint32(len(data)),
+		Version:    eiscpVersion,
+	}
+	copy(hdr.Magic[:], eiscpMagic)
+
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.BigEndian, &hdr)
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+var errBadFrame = errors.New("onkyo: malformed eISCP frame")
+
+func readMessage(r io.Reader) (*iscpMessage, error) {
+	var hdr eiscpHeader
+	if err := binary.Read(r, binary.BigEndian, &hdr); err != nil {
+		return nil, err
+	}
+	if string(hdr.Magic[:]) != eiscpMagic || hdr.HeaderSize < eiscpHeaderSize || hdr.DataSize > 4096 {
+		return nil, errBadFrame
+	}
+	if _, err := io.CopyN(ioutil.Discard, r, int64(hdr.HeaderSize-eiscpHeaderSize)); err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, hdr.DataSize)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+
+	// Messages end in any combination of EOF (0x1A), CR and LF.
+	data = bytes.TrimRight(data, "\x1a\r\n")
+	if len(data) < 5 || data[0] != iscpStart {
+		return nil, errBadFrame
+	}
+	return &iscpMessage{string(data[2:5]), string(data[5:])}, nil
+}
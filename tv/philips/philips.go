@@ -0,0 +1,352 @@
+package philips
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/DHowett/avantgarde/tv"
+)
+
+type Config struct {
+	Address   string
+	MonitorID uint8 `yaml:"monitor_id"`
+	Group     uint8
+}
+
+func (c Config) ModelSpecificRepresentation() interface{} {
+	return c
+}
+
+type sicpModel struct{}
+
+func (l *sicpModel) Initialize(rwc io.ReadWriteCloser, c tv.Config) (tv.TV, error) {
+	sicpc, ok := c.(*Config)
+	if !ok {
+		return nil, fmt.Errorf("philips: invalid config type %T", c)
+	}
+
+	if rwc == nil && sicpc.Address == "" {
+		return nil, errors.New("philips: neither a serial port nor an address was configured")
+	}
+
+	return &sicpTV{
+		config:  sicpc,
+		serial:  rwc != nil,
+		timeout: 5 * time.Second,
+		rwc:     rwc,
+	}, nil
+}
+
+func (l *sicpModel) NewConfig() tv.Config {
+	return &Config{MonitorID: 1}
+}
+
+var tvInputToSICP = map[tv.InputNumber]byte{
+	{tv.Composite, 1}: 0x01,
+	{tv.Component, 1}: 0x03,
+	{tv.PC, 1}:        0x05,
+	{tv.HDMI, 1}:      0x0D,
+	{tv.HDMI, 2}:      0x06,
+	{tv.HDMI, 3}:      0x0F,
+	{tv.HDMI, 4}:      0x19,
+	{tv.Special, 1}:   0x0A, // DisplayPort 1
+	{tv.Special, 2}:   0x07, // DisplayPort 2
+}
+
+var sicpInputToTV = map[byte]tv.InputNumber{}
+
+var tvAttributeToVideoParameter = map[tv.Attribute]int{
+	tv.Brightness: videoBrightness,
+	tv.Color:      videoColor,
+	tv.Contrast:   videoContrast,
+	tv.Sharpness:  videoSharpness,
+	tv.Tint:       videoTint,
+}
+
+func clamp(val int) byte {
+	switch {
+	case val < 0:
+		return 0
+	case val > 100:
+		return 100
+	default:
+		return byte(val)
+	}
+}
+
+type sicpTV struct {
+	config *Config
+	serial bool
+	// timeout bounds how long a command waits for the display's reply.
+	timeout time.Duration
+
+	mu  sync.Mutex
+	rwc io.ReadWriteCloser
+	// pending carries the result of a serial read that outlived the
+	// command it was reading the reply to.
+	pending chan sicpReply
+}
+
+type sicpReply struct {
+	resp *sicpFrame
+	err  error
+}
+
+func (sicp *sicpTV) connect() error {
+	if sicp.rwc != nil {
+		return nil
+	}
+
+	addr := sicp.config.Address
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "5000")
+	}
+	conn, err := net.DialTimeout("tcp", addr, 10*time.Second)
+	if err != nil {
+		return err
+	}
+	sicp.rwc = conn
+	return nil
+}
+
+func (sicp *sicpTV) disconnect() {
+	if sicp.serial {
+		return
+	}
+	sicp.rwc.Close()
+	sicp.rwc = nil
+}
+
+// send writes a single command frame and returns the display's reply.
+// Commands that only elicit an acknowledgement return the ACK frame, and a
+// NACK or NAV is surfaced as an error.
+func (sicp *sicpTV) send(data ...byte) (*sicpFrame, error) {
+	sicp.mu.Lock()
+	defer sicp.mu.Unlock()
+
+	if err := sicp.connect(); err != nil {
+		return nil, err
+	}
+
+	// A reply that arrived after its command timed out is stale.
+	select {
+	case <-sicp.pending:
+		sicp.pending = nil
+	default:
+	}
+
+	req := &sicpFrame{sicp.config.MonitorID, sicp.config.Group, data}
+	if _, err := sicp.rwc.Write(req.Serialize()); err != nil {
+		sicp.disconnect()
+		return nil, err
+	}
+
+	resp, err := sicp.readReply()
+	if err != nil {
+		sicp.disconnect()
+		return nil, err
+	}
+
+	if len(resp.Data) >= 2 && resp.Data[0] == cmdAck {
+		switch resp.Data[1] {
+		case ackOK:
+		case ackNotAvailable:
+			return nil, fmt.Errorf("philips: command not available: %w", tv.ErrUnsupported)
+		default:
+			return nil, errors.New("philips: invalid command")
+		}
+	}
+	return resp, nil
+}
+
+// readReply reads the display's reply frame, giving up after sicp.timeout.
+// Serial ports have no read deadline, so there the read is left running and
+// the next command picks it up.
+func (sicp *sicpTV) readReply() (*sicpFrame, error) {
+	if conn, ok := sicp.rwc.(net.Conn); ok {
+		conn.SetReadDeadline(time.Now().Add(sicp.timeout))
+		defer conn.SetReadDeadline(time.Time{})
+		resp, err := readFrame(conn)
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return nil, fmt.Errorf("philips: no reply: %w", tv.ErrTimeout)
+		}
+		return resp, err
+	}
+
+	if sicp.pending == nil {
+		pending := make(chan sicpReply, 1)
+		go func(r io.Reader) {
+			resp, err := readFrame(r)
+			pending <- sicpReply{resp, err}
+		}(sicp.rwc)
+		sicp.pending = pending
+	}
+	timer := time.NewTimer(sicp.timeout)
+	defer timer.Stop()
+	select {
+	case r := <-sicp.pending:
+		sicp.pending = nil
+		return r.resp, r.err
+	case <-timer.C:
+		return nil, fmt.Errorf("philips: no reply: %w", tv.ErrTimeout)
+	}
+}
+
+// query sends a Get command and returns the payload of its reply, less the
+// command byte.
+func (sicp *sicpTV) query(cmd byte, n int) ([]byte, error) {
+	resp, err := sicp.send(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Data) < n+1 || resp.Data[0] != cmd {
+		return nil, fmt.Errorf("philips: malformed reply to command %#02x", cmd)
+	}
+	return resp.Data[1:], nil
+}
+
+func (sicp *sicpTV) setVideoParameter(param int, val byte) error {
+	params, err := sicp.query(cmdGetVideo, videoParameterCount)
+	if err != nil {
+		return err
+	}
+
+	data := append([]byte{cmdSetVideo}, params[:videoParameterCount]...)
+	data[1+param] = val
+	_, err = sicp.send(data...)
+	return err
+}
+
+func (sicp *sicpTV) Do(op *tv.Op) error {
+	var data []byte
+	switch op.Attribute {
+	case tv.Power:
+		if op.Value.(bool) {
+			data = []byte{cmdSetPower, powerOn}
+		} else {
+			data = []byte{cmdSetPower, powerOff}
+		}
+	case tv.Volume:
+		switch op.Operator {
+		case tv.Set:
+			vol := clamp(op.Value.(int))
+			data = []byte{cmdSetVolume, vol, vol}
+		case tv.Increment, tv.Decrement:
+			cur, err := sicp.query(cmdGetVolume, 1)
+			if err != nil {
+				return err
+			}
+			vol := int(cur[0]) + 1
+			if op.Operator == tv.Decrement {
+				vol = int(cur[0]) - 1
+			}
+			data = []byte{cmdSetVolume, clamp(vol), clamp(vol)}
+		}
+	case tv.Mute:
+		if op.Value.(bool) {
+			data = []byte{cmdSetMute, 0x01}
+		} else {
+			data = []byte{cmdSetMute, 0x00}
+		}
+	case tv.Input:
+		source, ok := tvInputToSICP[op.Value.(tv.InputNumber)]
+		if ok {
+			data = []byte{cmdSetInputSource, source, 0x00, 0x00, 0x00}
+		}
+	case tv.Brightness, tv.Color, tv.Contrast, tv.Sharpness, tv.Tint:
+		return sicp.setVideoParameter(tvAttributeToVideoParameter[op.Attribute], clamp(op.Value.(int)))
+	case tv.Raw:
+		_, err := sicp.DoRaw(op.Value.([]byte))
+		return err
+	}
+
+	if data == nil {
+		return fmt.Errorf("philips: %w", tv.ErrUnsupported)
+	}
+	_, err := sicp.send(data...)
+	return err
+}
+
+// DoRaw sends the data bytes of a single command, which are framed with
+// the configured monitor ID and group, and returns the data of the reply.
+func (sicp *sicpTV) DoRaw(data []byte) ([]byte, error) {
+	if len(data) == 0 || len(data) > maxFrameData {
+		return nil, fmt.Errorf("philips: raw commands must be 1 to %d bytes: %w", maxFrameData, tv.ErrInvalidValue)
+	}
+	resp, err := sicp.send(data...)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
+
+func (sicp *sicpTV) State() (*tv.State, error) {
+	state := &tv.State{}
+
+	power, err := sicp.query(cmdGetPower, 1)
+	if err != nil {
+		return nil, err
+	}
+	state.Power = power[0] == powerOn
+	if !state.Power {
+		return state, nil
+	}
+
+	volume, err := sicp.query(cmdGetVolume, 1)
+	if err != nil {
+		return nil, err
+	}
+	state.Volume = int(volume[0])
+
+	mute, err := sicp.query(cmdGetMute, 1)
+	if err != nil {
+		return nil, err
+	}
+	state.Mute = mute[0] == 0x01
+
+	input, err := sicp.query(cmdGetInputSource, 1)
+	if err != nil {
+		return nil, err
+	}
+	state.Input = sicpInputToTV[input[0]]
+
+	return state, nil
+}
+
+func inputs() []tv.InputNumber {
+	inputs := make([]tv.InputNumber, 0, len(tvInputToSICP))
+	for k := range tvInputToSICP {
+		inputs = append(inputs, k)
+	}
+	tv.SortInputs(inputs)
+	return inputs
+}
+
+var capabilities = []tv.Capability{
+	{Attribute: tv.Power, Operators: []tv.Operator{tv.Set}},
+	{Attribute: tv.Volume, Operators: []tv.Operator{tv.Set, tv.Increment, tv.Decrement}, Min: 0, Max: 100},
+	{Attribute: tv.Mute, Operators: []tv.Operator{tv.Set}},
+	{Attribute: tv.Input, Operators: []tv.Operator{tv.Set}, Inputs: inputs()},
+	{Attribute: tv.Brightness, Operators: []tv.Operator{tv.Set}, Min: 0, Max: 100},
+	{Attribute: tv.Color, Operators: []tv.Operator{tv.Set}, Min: 0, Max: 100},
+	{Attribute: tv.Contrast, Operators: []tv.Operator{tv.Set}, Min: 0, Max: 100},
+	{Attribute: tv.Sharpness, Operators: []tv.Operator{tv.Set}, Min: 0, Max: 100},
+	{Attribute: tv.Tint, Operators: []tv.Operator{tv.Set}, Min: 0, Max: 100},
+	{Attribute: tv.Raw, Operators: []tv.Operator{tv.Set}},
+}
+
+func (sicp *sicpTV) Capabilities() []tv.Capability {
+	return capabilities
+}
+
+func init() {
+	for k, v := range tvInputToSICP {
+		sicpInputToTV[v] = k
+	}
+	tv.RegisterModel("philips", &sicpModel{})
+}
@@ -0,0 +1,119 @@
+package philips
+
+import "bytes"
+import "errors"
+import "io"
+import "net"
+import "testing"
+import "time"
+
+import "github.com/DHowett/avantgarde/tv"
+
+func TestSerialization(t *testing.T) {
+	f := &sicpFrame{
+		0x01,
+		0x00,
+		[]byte{cmdSetPower, powerOn},
+	}
+
+	expect := []byte{0x06, 0x01, 0x00, 0x18, 0x02, 0x1D}
+	if !bytes.Equal(f.Serialize(), expect) {
+		t.Errorf("Got %x instead of %x for serializing %v!", f.Serialize(), expect, f)
+	}
+
+	f = &sicpFrame{
+		0x01,
+		0x00,
+		[]byte{cmdSetInputSource, 0x0D, 0x00, 0x00, 0x00},
+	}
+
+	expect = []byte{0x09, 0x01, 0x00, 0xAC, 0x0D, 0x00, 0x00, 0x00, 0xA9}
+	if !bytes.Equal(f.Serialize(), expect) {
+		t.Errorf("Got %x instead of %x for serializing %v!", f.Serialize(), expect, f)
+	}
+}
+
+func TestDeserialization(t *testing.T) {
+	raw := []byte{0x06, 0x01, 0x00, 0x00, 0x00, 0x07}
+	f, err := readFrame(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Failed to parse %x: %v", raw, err)
+	}
+	if f.Control != 0x01 || !bytes.Equal(f.Data, []byte{cmdAck, ackOK}) {
+		t.Errorf("Got %v instead of an ACK from monitor 1 for %x!", f, raw)
+	}
+
+	if !bytes.Equal(f.Serialize(), raw) {
+		t.Errorf("Got %x instead of %x when round-tripping %v!", f.Serialize(), raw, f)
+	}
+
+	raw = []byte{0x06, 0x01, 0x00, 0x00, 0x00, 0x08}
+	if _, err = readFrame(bytes.NewReader(raw)); err != errFrameChecksum {
+		t.Errorf("Got %v instead of a checksum error for %x!", err, raw)
+	}
+
+	raw = []byte{0x06, 0x01, 0x00}
+	if _, err = readFrame(bytes.NewReader(raw)); err == nil {
+		t.Errorf("Parsed truncated frame %x without error!", raw)
+	}
+}
+
+func TestReplyTimeout(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		// Accept the command, but never answer it.
+		conn, err := l.Accept()
+		if err == nil {
+			defer conn.Close()
+			conn.Read(make([]byte, 64))
+			time.Sleep(time.Second)
+		}
+	}()
+
+	// The address carries its own port, which is used instead of 5000.
+	display, err := (&sicpModel{}).Initialize(nil, &Config{Address: l.Addr().String(), MonitorID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sicp := display.(*sicpTV)
+	sicp.timeout = 50 * time.Millisecond
+	if err := sicp.Do(&tv.Op{tv.Power, tv.Set, true}); !errors.Is(err, tv.ErrTimeout) {
+		t.Errorf("Got %v instead of a timeout!", err)
+	}
+}
+
+// silentPort is a serial port that swallows commands and reads whatever is
+// written to its pipe.
+type silentPort struct {
+	*io.PipeReader
+}
+
+func (silentPort) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func TestSerialReplyTimeout(t *testing.T) {
+	r, w := io.Pipe()
+	defer w.Close()
+	display, err := (&sicpModel{}).Initialize(silentPort{r}, &Config{MonitorID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sicp := display.(*sicpTV)
+	sicp.timeout = 50 * time.Millisecond
+
+	ack := &sicpFrame{1, 0, []byte{cmdAck, ackOK}}
+	go w.Write(ack.Serialize())
+	if err := sicp.Do(&tv.Op{tv.Power, tv.Set, true}); err != nil {
+		t.Errorf("Got %v for a command that was answered!", err)
+	}
+
+	// Nothing answers the second command; it must not hang.
+	if err := sicp.Do(&tv.Op{tv.Power, tv.Set, false}); !errors.Is(err, tv.ErrTimeout) {
+		t.Errorf("Got %v instead of a timeout!", err)
+	}
+}
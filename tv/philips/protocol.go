@@ -0,0 +1,106 @@
+package philips
+
+import (
+	"errors"
+	"io"
+)
+
+const (
+	cmdAck            byte = 0x00
+	cmdSetVideo       byte = 0x32
+	cmdGetVideo       byte = 0x33
+	cmdSetPower       byte = 0x18
+	cmdGetPower       byte = 0x19
+	cmdSetVolume      byte = 0x44
+	cmdGetVolume      byte = 0x45
+	cmdGetMute        byte = 0x46
+	cmdSetMute        byte = 0x47
+	cmdSetInputSource byte = 0xAC
+	cmdGetInputSource byte = 0xAD
+)
+
+const (
+	ackOK           byte = 0x00
+	ackNotOK        byte = 0x01
+	ackNotAvailable byte = 0x04
+)
+
+const (
+	powerOff byte = 0x01
+	powerOn  byte = 0x02
+)
+
+// Offsets of the individual settings within a Video Parameters message,
+// not counting the command byte.
+const (
+	videoBrightness = iota
+	videoColor
+	videoContrast
+	videoSharpness
+	videoTint
+	videoBlackLevel
+	videoGamma
+	videoParameterCount
+)
+
+// maxFrameData is the most data a frame can carry, as its length byte
+// also counts itself, the IDs and the checksum.
+const maxFrameData = 0xFF - 4
+
+// sicpFrame is a single SICP message: a length byte, the monitor ID
+// (control), the group ID, the data bytes and an XOR checksum.
+type sicpFrame struct {
+	Control byte
+	Group   byte
+	Data    []byte
+}
+
+func checksum(b []byte) byte {
+	var c byte
+	for _, v := range b {
+		c ^= v
+	}
+	return c
+}
+
+func (f *sicpFrame) Serialize() []byte {
+	buf := make([]byte, 0, len(f.Data)+4)
+	buf = append(buf, byte(len(f.Data)+4), f.Control, f.Group)
+	buf = append(buf, f.Data...)
+	return append(buf, checksum(buf))
+}
+
+var (
+	errShortFrame    = errors.New("philips: short frame")
+	errFrameChecksum = errors.New("philips: frame checksum mismatch")
+)
+
+func parseFrame(b []byte) (*sicpFrame, error) {
+	if len(b) < 4 || int(b[0]) != len(b) {
+		return nil, errShortFrame
+	}
+	if checksum(b[:len(b)-1]) != b[len(b)-1] {
+		return nil, errFrameChecksum
+	}
+	return &sicpFrame{
+		Control: b[1],
+		Group:   b[2],
+		Data:    b[3 : len(b)-1],
+	}, nil
+}
+
+func readFrame(r io.Reader) (*sicpFrame, error) {
+	var size [1]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return nil, err
+	}
+	if size[0] < 4 {
+		return nil, errShortFrame
+	}
+	buf := make([]byte, size[0])
+	buf[0] = size[0]
+	if _, err := io.ReadFull(r, buf[1:]); err != nil {
+		return nil, err
+	}
+	return parseFrame(buf)
+}
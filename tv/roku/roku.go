@@ -0,0 +1,201 @@
+package roku
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/DHowett/avantgarde/tv"
+)
+
+type Config struct {
+	Address string
+}
+
+func (c Config) ModelSpecificRepresentation() interface{} {
+	return c
+}
+
+// keyNamePattern matches the key names raw commands may send. They go into
+// the URL as they are, so any other character must already be escaped, as
+// in Lit_%40.
+var keyNamePattern = regexp.MustCompile(`^([A-Za-z0-9_]|%[0-9A-Fa-f]{2}){1,64}$`)
+
+type rokuModel struct{}
+
+func (l *rokuModel) Initialize(rwc io.ReadWriteCloser, c tv.Config) (tv.TV, error) {
+	rokuc, ok := c.(*Config)
+	if !ok {
+		return nil, fmt.Errorf("roku: invalid config type %T", c)
+	}
+
+	if rokuc.Address == "" {
+		return nil, errors.New("roku: no address configured")
+	}
+
+	host := rokuc.Address
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, "8060")
+	}
+
+	return &rokuTV{
+		config: rokuc,
+		base:   &url.URL{Scheme: "http", Host: host},
+		client: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func (l *rokuModel) NewConfig() tv.Config {
+	return &Config{}
+}
+
+var tvInputToRoku = map[tv.Connection]string{
+	tv.Coaxial:   "tvinput.dtv",
+	tv.Composite: "tvinput.cvbs",
+	tv.HDMI:      "tvinput.hdmi%d",
+}
+
+func inputApp(i tv.InputNumber) string {
+	app, ok := tvInputToRoku[i.Connection]
+	if !ok {
+		return ""
+	}
+	if i.Connection == tv.HDMI {
+		return fmt.Sprintf(app, i.Number)
+	}
+	return app
+}
+
+type deviceInfo struct {
+	PowerMode string `xml:"power-mode"`
+}
+
+type rokuTV struct {
+	config *Config
+	base   *url.URL
+	client *http.Client
+
+	// ECP only offers a mute toggle and cannot report the current mute
+	// state, so we remember what we last asked for.
+	mu   sync.Mutex
+	mute bool
+}
+
+// url returns the URL for an ECP path, which is already escaped.
+func (roku *rokuTV) url(path string) string {
+	u := *roku.base
+	u.Path, _ = url.PathUnescape(path)
+	u.RawPath = path
+	return u.String()
+}
+
+func (roku *rokuTV) post(path string) error {
+	resp, err := roku.client.Post(roku.url(path), "", nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("roku: POST %s: %s", path, resp.Status)
+	}
+	return nil
+}
+
+// keypress presses a key, named as keyNamePattern requires.
+func (roku *rokuTV) keypress(key string) error {
+	return roku.post("/keypress/" + key)
+}
+
+func (roku *rokuTV) Do(op *tv.Op) error {
+	switch op.Attribute {
+	case tv.Power:
+		if op.Value.(bool) {
+			return roku.keypress("PowerOn")
+		}
+		return roku.keypress("PowerOff")
+	case tv.Volume:
+		switch op.Operator {
+		case tv.Increment:
+			return roku.keypress("VolumeUp")
+		case tv.Decrement:
+			return roku.keypress("VolumeDown")
+		}
+	case tv.Mute:
+		roku.mu.Lock()
+		defer roku.mu.Unlock()
+		switch op.Operator {
+		case tv.Set:
+			if op.Value.(bool) == roku.mute {
+				return nil
+			}
+			fallthrough
+		case tv.Toggle:
+			if err := roku.keypress("VolumeMute"); err != nil {
+				return err
+			}
+			roku.mute = !roku.mute
+			return nil
+		}
+	case tv.Input:
+		app := inputApp(op.Value.(tv.InputNumber))
+		if app != "" {
+			return roku.post("/launch/" + app)
+		}
+	case tv.Raw:
+		// Raw commands are passed through as ECP key names, e.g. "Home".
+		// Keys become part of the request path, so only take names
+		// like "Home" or "Lit_%40".
+		key := string(op.Value.([]byte))
+		if !keyNamePattern.MatchString(key) {
+			return fmt.Errorf("roku: raw commands must be key names: %w", tv.ErrInvalidValue)
+		}
+		return roku.keypress(key)
+	}
+	return fmt.Errorf("roku: %w", tv.ErrUnsupported)
+}
+
+func (roku *rokuTV) State() (*tv.State, error) {
+	resp, err := roku.client.Get(roku.url("/query/device-info"))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("roku: GET /query/device-info: %s", resp.Status)
+	}
+
+	var info deviceInfo
+	if err := xml.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, err
+	}
+
+	roku.mu.Lock()
+	defer roku.mu.Unlock()
+	return &tv.State{
+		Power: info.PowerMode == "PowerOn",
+		Mute:  roku.mute,
+	}, nil
+}
+
+var capabilities = []tv.Capability{
+	{Attribute: tv.Power, Operators: []tv.Operator{tv.Set}},
+	{Attribute: tv.Volume, Operators: []tv.Operator{tv.Increment, tv.Decrement}},
+	{Attribute: tv.Mute, Operators: []tv.Operator{tv.Set, tv.Toggle}},
+	{Attribute: tv.Input, Operators: []tv.Operator{tv.Set}},
+	{Attribute: tv.Raw, Operators: []tv.Operator{tv.Set}},
+}
+
+func (roku *rokuTV) Capabilities() []tv.Capability {
+	return capabilities
+}
+
+func init() {
+	tv.RegisterModel("roku", &rokuModel{})
+}
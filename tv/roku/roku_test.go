@@ -0,0 +1,127 @@
+package roku
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/DHowett/avantgarde/tv"
+)
+
+// fakeRoku records every ECP request it receives and answers device-info
+// queries with the configured power mode.
+type fakeRoku struct {
+	mu        sync.Mutex
+	requests  []string
+	powerMode string
+}
+
+func (f *fakeRoku) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.EscapedPath())
+
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/query/device-info":
+		w.Write([]byte(`<?xml version="1.0" encoding="UTF-8" ?>
+<device-info>
+	<model-name>TCL 55S405</model-name>
+	<power-mode>` + f.powerMode + `</power-mode>
+</device-info>`))
+	case r.Method == "POST" && (strings.HasPrefix(r.URL.Path, "/keypress/") || strings.HasPrefix(r.URL.Path, "/launch/")):
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newTestRoku(t *testing.T) (*fakeRoku, tv.TV) {
+	fake := &fakeRoku{powerMode: "PowerOn"}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	roku, err := (&rokuModel{}).Initialize(nil, &Config{Address: strings.TrimPrefix(srv.URL, "http://")})
+	if err != nil {
+		t.Fatalf("Failed to initialize: %v", err)
+	}
+	return fake, roku
+}
+
+func TestDo(t *testing.T) {
+	fake, roku := newTestRoku(t)
+
+	ops := []*tv.Op{
+		{tv.Power, tv.Set, true},
+		{tv.Volume, tv.Increment, 1},
+		{tv.Mute, tv.Set, true},
+		{tv.Mute, tv.Set, true},
+		{tv.Mute, tv.Toggle, nil},
+		{tv.Input, tv.Set, tv.InputNumber{tv.HDMI, 2}},
+		{tv.Raw, tv.Set, []byte("Home")},
+		{tv.Raw, tv.Set, []byte("Lit_%40")},
+	}
+	for _, op := range ops {
+		if err := roku.Do(op); err != nil {
+			t.Errorf("Failed to do %v: %v", op, err)
+		}
+	}
+
+	expect := []string{
+		"POST /keypress/PowerOn",
+		"POST /keypress/VolumeUp",
+		"POST /keypress/VolumeMute",
+		"POST /keypress/VolumeMute",
+		"POST /launch/tvinput.hdmi2",
+		"POST /keypress/Home",
+		"POST /keypress/Lit_%40",
+	}
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if strings.Join(fake.requests, "\n") != strings.Join(expect, "\n") {
+		t.Errorf("Got requests %q instead of %q!", fake.requests, expect)
+	}
+
+	if err := roku.Do(&tv.Op{tv.Volume, tv.Set, 10}); err == nil {
+		t.Errorf("Setting an absolute volume should be unsupported!")
+	}
+}
+
+func TestState(t *testing.T) {
+	fake, roku := newTestRoku(t)
+
+	state, err := roku.State()
+	if err != nil {
+		t.Fatalf("Failed to get state: %v", err)
+	}
+	if !state.Power {
+		t.Errorf("Got power off instead of on for power mode %s!", fake.powerMode)
+	}
+
+	fake.mu.Lock()
+	fake.powerMode = "DisplayOff"
+	fake.mu.Unlock()
+	state, err = roku.State()
+	if err != nil {
+		t.Fatalf("Failed to get state: %v", err)
+	}
+	if state.Power {
+		t.Errorf("Got power on instead of off for power mode %s!", fake.powerMode)
+	}
+}
+
+func TestRawKeyNames(t *testing.T) {
+	fake, roku := newTestRoku(t)
+
+	for _, key := range []string{"", "../launch/12", "Home?x=1", "Lit_%4", "Lit_%zz", "Lit_@"} {
+		if err := roku.Do(&tv.Op{tv.Raw, tv.Set, []byte(key)}); !errors.Is(err, tv.ErrInvalidValue) {
+			t.Errorf("Got %v instead of an invalid value error for %q!", err, key)
+		}
+	}
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if len(fake.requests) != 0 {
+		t.Errorf("Got requests %q for invalid keys!", fake.requests)
+	}
+}
@@ -0,0 +1,34 @@
+package sharp
+
+import (
+	"strings"
+)
+
+const (
+	cmdPower          = `POWR`
+	cmdVolume         = `VOLM`
+	cmdMute           = `MUTE`
+	cmdInputTV        = `ITVD`
+	cmdInput          = `IAVD`
+	cmdAnalogChannel  = `DCCH`
+	cmdDigitalAirTwo  = `DA2P`
+	cmdRemoteKey      = `RCKY`
+	parameterQuery    = `?`
+	responseOK        = `OK`
+	responseError     = `ERR`
+	responseLoginName = `Login:`
+	responsePassword  = `Password:`
+)
+
+const (
+	muteToggle = 0
+	muteOn     = 1
+	muteOff    = 2
+)
+
+func padParameter(s string) string {
+	if len(s) > 4 {
+		s = s[:4]
+	}
+	return s + strings.Repeat(" ", 4-len(s))
+}
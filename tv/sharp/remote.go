@@ -0,0 +1,8 @@
+package sharp
+
+type remoteKey int
+
+const (
+	RKVolumeDown remoteKey = 32
+	RKVolumeUp   remoteKey = 33
+)
@@ -0,0 +1,412 @@
+package sharp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DHowett/avantgarde/tv"
+)
+
+// Aquos sets take volume in the range 0-60.
+const maxVolume = 60
+
+type Config struct {
+	Address  string
+	Username string
+	Password string
+}
+
+func (c Config) ModelSpecificRepresentation() interface{} {
+	return c
+}
+
+type aquosModel struct{}
+
+func (l *aquosModel) Initialize(rwc io.ReadWriteCloser, c tv.Config) (tv.TV, error) {
+	aquosc, ok := c.(*Config)
+	if !ok {
+		return nil, fmt.Errorf("sharp: invalid config type %T", c)
+	}
+
+	if rwc == nil && aquosc.Address == "" {
+		return nil, errors.New("sharp: neither a serial port nor an address was configured")
+	}
+
+	aquos := &aquosTV{
+		config:  aquosc,
+		serial:  rwc != nil,
+		timeout: 5 * time.Second,
+	}
+	if rwc != nil {
+		aquos.attach(rwc)
+	}
+	return aquos, nil
+}
+
+func (l *aquosModel) NewConfig() tv.Config {
+	return &Config{}
+}
+
+type aquosCommand struct {
+	command string
+	data    interface{}
+}
+
+func (c *aquosCommand) Serialize() []byte {
+	d := c.data
+	if v, ok := d.(bool); ok {
+		if v {
+			d = uint8(1)
+		} else {
+			d = uint8(0)
+		}
+	}
+	return []byte(c.command + padParameter(fmt.Sprintf("%v", d)) + "\r")
+}
+
+// tvInputToAquos holds the IAVD number of the first input of each kind;
+// HDMI inputs are handled separately as they occupy IAVD1 through IAVD4.
+var tvInputToAquos = map[tv.Connection]int{
+	tv.Component: 5,
+	tv.Composite: 6,
+	tv.PC:        8,
+}
+
+var aquosInputToTV = map[int]tv.InputNumber{
+	1: {tv.HDMI, 1},
+	2: {tv.HDMI, 2},
+	3: {tv.HDMI, 3},
+	4: {tv.HDMI, 4},
+	5: {tv.Component, 1},
+	6: {tv.Composite, 1},
+	7: {tv.Composite, 2},
+	8: {tv.PC, 1},
+}
+
+func inputCommand(i tv.InputNumber) *aquosCommand {
+	n := i.Number
+	if n < 1 {
+		n = 1
+	}
+
+	switch i.Connection {
+	case tv.Coaxial:
+		return &aquosCommand{cmdInputTV, 0}
+	case tv.HDMI:
+		return &aquosCommand{cmdInput, n}
+	}
+
+	base, ok := tvInputToAquos[i.Connection]
+	if !ok {
+		return nil
+	}
+	return &aquosCommand{cmdInput, base + n - 1}
+}
+
+func channelTuningCommand(t tv.Tune) *aquosCommand {
+	switch cht := t.C.(type) {
+	case tv.AnalogChannel:
+		return &aquosCommand{cmdAnalogChannel, uint(cht)}
+	case tv.DigitalChannel:
+		// DA2P takes a two-digit major and a two-digit minor channel number
+		if cht.Ch > 99 || cht.Sub > 99 {
+			return nil
+		}
+		return &aquosCommand{cmdDigitalAirTwo, fmt.Sprintf("%02d%02d", cht.Ch, cht.Sub)}
+	default:
+		return nil
+	}
+}
+
+func clamp(val, max int) int {
+	switch {
+	case val < 0:
+		return 0
+	case val > max:
+		return max
+	default:
+		return val
+	}
+}
+
+type aquosTV struct {
+	config *Config
+	serial bool
+	// timeout bounds how long a command waits for the set's reply.
+	timeout time.Duration
+
+	mu sync.Mutex
+	r  *bufio.Reader
+	w  io.Writer
+	c  io.Closer
+	// pending carries the result of a serial read that outlived the
+	// command it was reading the reply to.
+	pending chan aquosReply
+}
+
+type aquosReply struct {
+	resp string
+	err  error
+}
+
+func (aquos *aquosTV) attach(rwc io.ReadWriteCloser) {
+	aquos.r = bufio.NewReader(rwc)
+	aquos.w = rwc
+	aquos.c = rwc
+}
+
+func (aquos *aquosTV) readUntil(delim byte) (string, error) {
+	s, err := aquos.r.ReadString(delim)
+	return strings.TrimSpace(s), err
+}
+
+// login answers the Login:/Password: prompts the set issues when network
+// authentication is enabled.
+func (aquos *aquosTV) login() error {
+	prompt, err := aquos.readUntil(':')
+	if err != nil {
+		return err
+	}
+	if prompt != responseLoginName {
+		return fmt.Errorf("sharp: unexpected login prompt %q", prompt)
+	}
+	io.WriteString(aquos.w, aquos.config.Username+"\r")
+
+	prompt, err = aquos.readUntil(':')
+	if err != nil {
+		return err
+	}
+	if prompt != responsePassword {
+		return fmt.Errorf("sharp: unexpected password prompt %q", prompt)
+	}
+	_, err = io.WriteString(aquos.w, aquos.config.Password+"\r")
+	return err
+}
+
+func (aquos *aquosTV) connect() error {
+	if aquos.r != nil {
+		return nil
+	}
+
+	addr := aquos.config.Address
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "10002")
+	}
+	conn, err := net.DialTimeout("tcp", addr, 10*time.Second)
+	if err != nil {
+		return err
+	}
+	aquos.attach(conn)
+
+	if aquos.config.Username != "" {
+		conn.SetDeadline(time.Now().Add(10 * time.Second))
+		err = aquos.login()
+		conn.SetDeadline(time.Time{})
+		if err != nil {
+			aquos.disconnect()
+			return err
+		}
+	}
+	return nil
+}
+
+func (aquos *aquosTV) disconnect() {
+	if aquos.serial {
+		return
+	}
+	aquos.c.Close()
+	aquos.r, aquos.w, aquos.c = nil, nil, nil
+}
+
+// send writes a single serialized command and waits for the set's one-line
+// reply. The reply is returned verbatim unless the set reported an error.
+func (aquos *aquosTV) send(b []byte) (string, error) {
+	aquos.mu.Lock()
+	defer aquos.mu.Unlock()
+
+	if err := aquos.connect(); err != nil {
+		return "", err
+	}
+
+	// A reply that arrived after its command timed out is stale.
+	select {
+	case <-aquos.pending:
+		aquos.pending = nil
+	default:
+	}
+
+	if _, err := aquos.w.Write(b); err != nil {
+		aquos.disconnect()
+		return "", err
+	}
+
+	resp, err := aquos.readReply()
+	if err != nil {
+		aquos.disconnect()
+		return "", err
+	}
+
+	if resp == responseError {
+		return "", errors.New("sharp: invalid command")
+	}
+	return resp, nil
+}
+
+// readReply reads the set's one-line reply, giving up after aquos.timeout.
+// Serial ports have no read deadline, so there the read is left running and
+// the next command picks it up.
+func (aquos *aquosTV) readReply() (string, error) {
+	if conn, ok := aquos.c.(net.Conn); ok {
+		conn.SetReadDeadline(time.Now().Add(aquos.timeout))
+		defer conn.SetReadDeadline(time.Time{})
+		resp, err := aquos.readUntil('\r')
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return "", fmt.Errorf("sharp: no reply: %w", tv.ErrTimeout)
+		}
+		return resp, err
+	}
+
+	if aquos.pending == nil {
+		pending := make(chan aquosReply, 1)
+		go func() {
+			resp, err := aquos.readUntil('\r')
+			pending <- aquosReply{resp, err}
+		}()
+		aquos.pending = pending
+	}
+	timer := time.NewTimer(aquos.timeout)
+	defer timer.Stop()
+	select {
+	case r := <-aquos.pending:
+		aquos.pending = nil
+		return r.resp, r.err
+	case <-timer.C:
+		return "", fmt.Errorf("sharp: no reply: %w", tv.ErrTimeout)
+	}
+}
+
+func (aquos *aquosTV) query(command string) (string, error) {
+	return aquos.send((&aquosCommand{command, parameterQuery}).Serialize())
+}
+
+func (aquos *aquosTV) queryInt(command string) (int, error) {
+	resp, err := aquos.query(command)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(resp)
+}
+
+func (aquos *aquosTV) Do(op *tv.Op) error {
+	var cmd *aquosCommand
+	switch op.Attribute {
+	case tv.Power:
+		cmd = &aquosCommand{cmdPower, op.Value}
+	case tv.Volume:
+		switch op.Operator {
+		case tv.Set:
+			cmd = &aquosCommand{cmdVolume, fmt.Sprintf("%02d", clamp(op.Value.(int), maxVolume))}
+		case tv.Increment:
+			cmd = &aquosCommand{cmdRemoteKey, RKVolumeUp}
+		case tv.Decrement:
+			cmd = &aquosCommand{cmdRemoteKey, RKVolumeDown}
+		}
+	case tv.Mute:
+		switch op.Operator {
+		case tv.Set:
+			if op.Value.(bool) {
+				cmd = &aquosCommand{cmdMute, muteOn}
+			} else {
+				cmd = &aquosCommand{cmdMute, muteOff}
+			}
+		case tv.Toggle:
+			cmd = &aquosCommand{cmdMute, muteToggle}
+		}
+	case tv.Input:
+		cmd = inputCommand(op.Value.(tv.InputNumber))
+	case tv.Tuning:
+		cmd = channelTuningCommand(op.Value.(tv.Tune))
+	case tv.Raw:
+		_, err := aquos.DoRaw(op.Value.([]byte))
+		return err
+	}
+
+	if cmd == nil {
+		return fmt.Errorf("sharp: %w", tv.ErrUnsupported)
+	}
+	_, err := aquos.send(cmd.Serialize())
+	return err
+}
+
+// DoRaw sends a raw command, which must be a four-character command and a
+// four-character parameter, and returns the set's reply.
+func (aquos *aquosTV) DoRaw(buf []byte) ([]byte, error) {
+	buf = bytes.TrimSuffix(buf, []byte{0x0D})
+	if len(buf) != 8 {
+		return nil, fmt.Errorf("sharp: raw commands must be eight characters, like \"POWR1   \": %w", tv.ErrInvalidValue)
+	}
+	resp, err := aquos.send(append(buf, 0x0D))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(resp), nil
+}
+
+func (aquos *aquosTV) State() (*tv.State, error) {
+	state := &tv.State{}
+
+	power, err := aquos.queryInt(cmdPower)
+	if err != nil {
+		return nil, err
+	}
+	state.Power = power == 1
+	if !state.Power {
+		// A set in standby rejects every other enquiry.
+		return state, nil
+	}
+
+	if state.Volume, err = aquos.queryInt(cmdVolume); err != nil {
+		return nil, err
+	}
+
+	mute, err := aquos.queryInt(cmdMute)
+	if err != nil {
+		return nil, err
+	}
+	state.Mute = mute == muteOn
+
+	// IAVD? is rejected while the tuner is the active input.
+	if input, err := aquos.queryInt(cmdInput); err == nil {
+		state.Input = aquosInputToTV[input]
+	} else {
+		state.Input = tv.InputNumber{tv.Coaxial, 1}
+	}
+
+	return state, nil
+}
+
+var capabilities = []tv.Capability{
+	{Attribute: tv.Power, Operators: []tv.Operator{tv.Set}},
+	{Attribute: tv.Volume, Operators: []tv.Operator{tv.Set, tv.Increment, tv.Decrement}, Min: 0, Max: maxVolume},
+	{Attribute: tv.Mute, Operators: []tv.Operator{tv.Set, tv.Toggle}},
+	{Attribute: tv.Input, Operators: []tv.Operator{tv.Set}},
+	{Attribute: tv.Tuning, Operators: []tv.Operator{tv.Set}},
+	{Attribute: tv.Raw, Operators: []tv.Operator{tv.Set}},
+}
+
+func (aquos *aquosTV) Capabilities() []tv.Capability {
+	return capabilities
+}
+
+func init() {
+	tv.RegisterModel("sharp", &aquosModel{})
+}
@@ -0,0 +1,114 @@
+package sharp
+
+import "bytes"
+import "errors"
+import "io"
+import "net"
+import "testing"
+import "time"
+
+import "github.com/DHowett/avantgarde/tv"
+
+func TestSerialization(t *testing.T) {
+	sc := &aquosCommand{cmdPower, true}
+
+	expect := []byte("POWR1   \x0D")
+	if !bytes.Equal(sc.Serialize(), expect) {
+		t.Errorf("Got %q instead of %q for serializing %v!", sc.Serialize(), expect, sc)
+	}
+
+	sc = &aquosCommand{cmdPower, parameterQuery}
+
+	expect = []byte("POWR?   \x0D")
+	if !bytes.Equal(sc.Serialize(), expect) {
+		t.Errorf("Got %q instead of %q for serializing %v!", sc.Serialize(), expect, sc)
+	}
+}
+
+func TestChannelTuning(t *testing.T) {
+	sc := channelTuningCommand(tv.Tune{0x01, tv.AnalogChannel(12)})
+
+	expect := []byte("DCCH12  \x0D")
+	if !bytes.Equal(sc.Serialize(), expect) {
+		t.Errorf("Got %q instead of %q for serializing %v!", sc.Serialize(), expect, sc)
+	}
+
+	sc = channelTuningCommand(tv.Tune{0x01, tv.DigitalChannel{7, 1}})
+
+	expect = []byte("DA2P0701\x0D")
+	if !bytes.Equal(sc.Serialize(), expect) {
+		t.Errorf("Got %q instead of %q for serializing %v!", sc.Serialize(), expect, sc)
+	}
+
+	if sc = channelTuningCommand(tv.Tune{0x01, tv.DigitalChannel{123, 1}}); sc != nil {
+		t.Errorf("Got %v for a three-digit major channel; DA2P cannot express it!", sc)
+	}
+}
+
+func TestRawValidation(t *testing.T) {
+	aquos := &aquosTV{}
+	for _, raw := range []string{"", "POWR1", "POWR1    \r", "POWR1      "} {
+		if _, err := aquos.DoRaw([]byte(raw)); !errors.Is(err, tv.ErrInvalidValue) {
+			t.Errorf("Got %v instead of an invalid value error for %q!", err, raw)
+		}
+	}
+}
+
+func TestReplyTimeout(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		// Accept the command, but never answer it.
+		conn, err := l.Accept()
+		if err == nil {
+			defer conn.Close()
+			conn.Read(make([]byte, 64))
+			time.Sleep(time.Second)
+		}
+	}()
+
+	// The address carries its own port, which is used instead of 10002.
+	set, err := (&aquosModel{}).Initialize(nil, &Config{Address: l.Addr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	aquos := set.(*aquosTV)
+	aquos.timeout = 50 * time.Millisecond
+	if err := aquos.Do(&tv.Op{tv.Power, tv.Set, true}); !errors.Is(err, tv.ErrTimeout) {
+		t.Errorf("Got %v instead of a timeout!", err)
+	}
+}
+
+// silentPort is a serial port that swallows commands and reads whatever is
+// written to its pipe.
+type silentPort struct {
+	*io.PipeReader
+}
+
+func (silentPort) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func TestSerialReplyTimeout(t *testing.T) {
+	r, w := io.Pipe()
+	defer w.Close()
+	set, err := (&aquosModel{}).Initialize(silentPort{r}, &Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	aquos := set.(*aquosTV)
+	aquos.timeout = 50 * time.Millisecond
+
+	go io.WriteString(w, "OK\r")
+	if err := aquos.Do(&tv.Op{tv.Power, tv.Set, true}); err != nil {
+		t.Errorf("Got %v for a command that was answered!", err)
+	}
+
+	// Nothing answers the second command; it must not hang.
+	if err := aquos.Do(&tv.Op{tv.Power, tv.Set, false}); !errors.Is(err, tv.ErrTimeout) {
+		t.Errorf("Got %v instead of a timeout!", err)
+	}
+}
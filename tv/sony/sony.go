@@ -92,6 +92,9 @@ func (c *braviaCommand) Serialize() []byte {
 type braviaRawCommand []byte
 
 func (c braviaRawCommand) ID() string {
+	if len(c) < 7 {
+		return ""
+	}
 	return string(c[3:7])
 }
 
@@ -187,7 +190,7 @@ func (brv *braviaTV) when(ev tv.Attribute, handler func(*tv.Op)) {
 
 func (bravia *braviaTV) Do(op *tv.Op) error {
 	if bravia.reqCh == nil {
-		return errors.New("tv not connected")
+		return fmt.Errorf("bravia: %w", tv.ErrDisconnected)
 	}
 	var cmd request
 	switch op.Attribute {
@@ -225,14 +228,19 @@ func (bravia *braviaTV) Do(op *tv.Op) error {
 		}
 	case tv.Raw:
 		buf := op.Value.([]byte)
-		if buf[len(buf)-1] != 0x0A {
+		if len(buf) > 0 && buf[len(buf)-1] != 0x0A {
 			buf = append(buf, 0x0A)
 		}
+		// Every message is 24 bytes: "*S", its type, a four-letter
+		// command, 16 characters of parameter and a newline.
+		if len(buf) != 24 || buf[0] != '*' || buf[1] != 'S' {
+			return fmt.Errorf("bravia: raw commands must look like *SCPOWR0000000000000001: %w", tv.ErrInvalidValue)
+		}
 		cmd = braviaRawCommand(buf)
 	}
 
 	if cmd == nil {
-		return errors.New("bravia: unsupported")
+		return fmt.Errorf("bravia: %w", tv.ErrUnsupported)
 	} else {
 		err := <-bravia.send(cmd)
 		return err
@@ -433,6 +441,25 @@ func (brv *braviaTV) run() {
 	}
 }
 
+var capabilities = []tv.Capability{
+	{Attribute: tv.Power, Operators: []tv.Operator{tv.Set}},
+	{Attribute: tv.Volume, Operators: []tv.Operator{tv.Set, tv.Increment, tv.Decrement}, Min: 0, Max: 100},
+	{Attribute: tv.Mute, Operators: []tv.Operator{tv.Set}},
+	{Attribute: tv.Screen, Operators: []tv.Operator{tv.Set, tv.Toggle}},
+	{Attribute: tv.Input, Operators: []tv.Operator{tv.Set}},
+	{Attribute: tv.Tuning, Operators: []tv.Operator{tv.Set}},
+	{Attribute: tv.PIP, Operators: []tv.Operator{tv.Set, tv.Toggle}},
+	{Attribute: tv.Raw, Operators: []tv.Operator{tv.Set}},
+}
+
+func (bravia *braviaTV) Capabilities() []tv.Capability {
+	return capabilities
+}
+
+func (bravia *braviaTV) Connected() bool {
+	return bravia.reqCh != nil
+}
+
 func init() {
 	tv.RegisterModel("bravia", &braviaModel{})
 }
@@ -1,8 +1,10 @@
 package tv
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"sort"
 )
 
 type Attribute uint
@@ -28,6 +30,43 @@ const (
 	Raw
 )
 
+var attributeNames = map[Attribute]string{
+	Power:            "power",
+	Volume:           "volume",
+	Mute:             "mute",
+	OSD:              "osd",
+	Input:            "input",
+	Tuning:           "tuning",
+	Screen:           "screen",
+	Contrast:         "contrast",
+	Brightness:       "brightness",
+	Color:            "color",
+	Tint:             "tint",
+	Sharpness:        "sharpness",
+	Lock:             "lock",
+	AudioBalance:     "balance",
+	ColorTemperature: "color_temperature",
+	Backlight:        "backlight",
+	PIP:              "pip",
+	Raw:              "raw",
+}
+
+func (a Attribute) String() string {
+	if name, ok := attributeNames[a]; ok {
+		return name
+	}
+	return fmt.Sprintf("Attribute(%d)", uint(a))
+}
+
+func ParseAttribute(name string) (Attribute, error) {
+	for a, n := range attributeNames {
+		if n == name {
+			return a, nil
+		}
+	}
+	return 0, fmt.Errorf("tv: unknown attribute %s", name)
+}
+
 type Connection uint
 
 const (
@@ -57,6 +96,30 @@ const (
 	Query
 )
 
+var operatorNames = map[Operator]string{
+	Set:       "set",
+	Increment: "increment",
+	Decrement: "decrement",
+	Toggle:    "toggle",
+	Query:     "query",
+}
+
+func (o Operator) String() string {
+	if name, ok := operatorNames[o]; ok {
+		return name
+	}
+	return fmt.Sprintf("Operator(%d)", uint(o))
+}
+
+func ParseOperator(name string) (Operator, error) {
+	for o, n := range operatorNames {
+		if n == name {
+			return o, nil
+		}
+	}
+	return 0, fmt.Errorf("tv: unknown operator %s", name)
+}
+
 type Antenna uint
 
 type Channel interface{}
@@ -86,6 +149,16 @@ type State struct {
 	Input   InputNumber
 }
 
+// Errors for conditions common to every model. Models wrap them with their
+// own prefix, e.g. fmt.Errorf("lg: %w", tv.ErrUnsupported), so that callers
+// can tell them apart with errors.Is.
+var (
+	ErrUnsupported  = errors.New("unsupported")
+	ErrInvalidValue = errors.New("invalid value")
+	ErrTimeout      = errors.New("timed out")
+	ErrDisconnected = errors.New("not connected")
+)
+
 type Config interface {
 	ModelSpecificRepresentation() interface{}
 }
@@ -100,12 +173,78 @@ type TV interface {
 	State() (*State, error)
 }
 
+// RawReplier is implemented by TVs that can hand back the reply to a raw
+// command, which Do would discard.
+type RawReplier interface {
+	DoRaw(cmd []byte) ([]byte, error)
+}
+
+// Switcher is implemented by devices, such as HDMI matrices, that can send
+// any of their inputs to any of their outputs. Inputs and outputs are
+// numbered from 1.
+type Switcher interface {
+	Route(input, output int) error
+	// Routes returns the crosspoint table, mapping each output to the
+	// input it currently shows.
+	Routes() (map[int]int, error)
+}
+
+// Pairer is implemented by TVs that only accept commands once paired by
+// entering a PIN shown on their screen.
+type Pairer interface {
+	BeginPairing() error
+	FinishPairing(pin string) error
+}
+
+// Capability describes an attribute that a TV supports: the operators it
+// accepts and, for numeric attributes, the range of values Set takes.
+type Capability struct {
+	Attribute Attribute
+	Operators []Operator
+	Min, Max  int
+	// Inputs lists the inputs that can be selected, for TVs with a fixed
+	// set of them. It is empty when any InputNumber may be tried.
+	Inputs []InputNumber
+}
+
+// Capable is implemented by TVs that can report what they support.
+type Capable interface {
+	Capabilities() []Capability
+}
+
+// Connector is implemented by TVs that keep a connection to the set open
+// and can report whether it is currently established.
+type Connector interface {
+	Connected() bool
+}
+
+// SortInputs orders inputs by connection and then by number, so that models
+// can list the keys of their input tables in a stable order.
+func SortInputs(inputs []InputNumber) {
+	sort.Slice(inputs, func(i, j int) bool {
+		if inputs[i].Connection != inputs[j].Connection {
+			return inputs[i].Connection < inputs[j].Connection
+		}
+		return inputs[i].Number < inputs[j].Number
+	})
+}
+
 var tvModels = map[string]TVModel{}
 
 func RegisterModel(name string, m TVModel) {
 	tvModels[name] = m
 }
 
+// Models returns the names of every registered model, in order.
+func Models() []string {
+	var names []string
+	for name := range tvModels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 func New(model string, rwc io.ReadWriteCloser, config Config) (TV, error) {
 	tvm, ok := tvModels[model]
 	if !ok {
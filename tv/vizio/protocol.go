@@ -0,0 +1,91 @@
+package vizio
+
+import (
+	"encoding/json"
+)
+
+const (
+	pathPairingStart = `/pairing/start`
+	pathPairingPair  = `/pairing/pair`
+	pathKeyCommand   = `/key_command/`
+	pathPowerMode    = `/state/device/power_mode`
+	pathVolume       = `/menu_native/dynamic/tv_settings/audio/volume`
+	pathMute         = `/menu_native/dynamic/tv_settings/audio/mute`
+	pathCurrentInput = `/menu_native/dynamic/tv_settings/devices/current_input`
+)
+
+const (
+	resultSuccess = `SUCCESS`
+	requestModify = `MODIFY`
+	actionPress   = `KEYPRESS`
+	headerAuth    = `AUTH`
+	muteOn        = `On`
+)
+
+type keyCode struct {
+	Codeset int `json:"CODESET"`
+	Code    int `json:"CODE"`
+}
+
+var (
+	keyPowerOff   = keyCode{11, 0}
+	keyPowerOn    = keyCode{11, 1}
+	keyVolumeDown = keyCode{5, 0}
+	keyVolumeUp   = keyCode{5, 1}
+	keyMuteOff    = keyCode{5, 2}
+	keyMuteOn     = keyCode{5, 3}
+	keyMuteToggle = keyCode{5, 4}
+)
+
+type keyPress struct {
+	keyCode
+	Action string `json:"ACTION"`
+}
+
+type keyCommandRequest struct {
+	KeyList []keyPress `json:"KEYLIST"`
+}
+
+type pairingStartRequest struct {
+	DeviceID   string `json:"DEVICE_ID"`
+	DeviceName string `json:"DEVICE_NAME"`
+}
+
+type pairingStartItem struct {
+	PairingReqToken int `json:"PAIRING_REQ_TOKEN"`
+	ChallengeType   int `json:"CHALLENGE_TYPE"`
+}
+
+type pairingPairRequest struct {
+	DeviceID        string `json:"DEVICE_ID"`
+	ChallengeType   int    `json:"CHALLENGE_TYPE"`
+	ResponseValue   string `json:"RESPONSE_VALUE"`
+	PairingReqToken int    `json:"PAIRING_REQ_TOKEN"`
+}
+
+type pairingPairItem struct {
+	AuthToken string `json:"AUTH_TOKEN"`
+}
+
+type settingItem struct {
+	HashVal int64           `json:"HASHVAL"`
+	Name    string          `json:"NAME,omitempty"`
+	Value   json.RawMessage `json:"VALUE"`
+}
+
+type modifyRequest struct {
+	Request string      `json:"REQUEST"`
+	HashVal int64       `json:"HASHVAL"`
+	Value   interface{} `json:"VALUE"`
+}
+
+// response is the envelope around every SmartCast reply. Pairing replies
+// carry a single ITEM, settings carry a list of ITEMS.
+type response struct {
+	Status struct {
+		Result string `json:"RESULT"`
+		Detail string `json:"DETAIL"`
+	} `json:"STATUS"`
+	Item  json.RawMessage `json:"ITEM"`
+	Items []settingItem   `json:"ITEMS"`
+}
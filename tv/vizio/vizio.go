@@ -0,0 +1,347 @@
+package vizio
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DHowett/avantgarde/tv"
+)
+
+type Config struct {
+	Address  string
+	DeviceID string `yaml:"device_id"`
+	// TokenFile stores the auth token issued when the set is paired.
+	TokenFile string `yaml:"token_file"`
+}
+
+func (c Config) ModelSpecificRepresentation() interface{} {
+	return c
+}
+
+type smartcastModel struct{}
+
+func (l *smartcastModel) Initialize(rwc io.ReadWriteCloser, c tv.Config) (tv.TV, error) {
+	vizioc, ok := c.(*Config)
+	if !ok {
+		return nil, fmt.Errorf("vizio: invalid config type %T", c)
+	}
+
+	if vizioc.Address == "" {
+		return nil, errors.New("vizio: no address configured")
+	}
+	if vizioc.TokenFile == "" {
+		vizioc.TokenFile = "vizio-" + vizioc.Address + ".token"
+	}
+
+	return newVizioTV(vizioc), nil
+}
+
+func (l *smartcastModel) NewConfig() tv.Config {
+	return &Config{DeviceID: "avantgarde"}
+}
+
+func inputName(i tv.InputNumber) string {
+	switch i.Connection {
+	case tv.Coaxial:
+		return "TV"
+	case tv.HDMI:
+		return fmt.Sprintf("HDMI-%d", i.Number)
+	case tv.Component, tv.Composite:
+		return "COMP"
+	case tv.Special:
+		return "CAST"
+	}
+	return ""
+}
+
+func nameInput(name string) tv.InputNumber {
+	switch {
+	case name == "TV":
+		return tv.InputNumber{tv.Coaxial, 1}
+	case strings.HasPrefix(name, "HDMI-"):
+		n, _ := strconv.Atoi(strings.TrimPrefix(name, "HDMI-"))
+		return tv.InputNumber{tv.HDMI, n}
+	case name == "COMP":
+		return tv.InputNumber{tv.Component, 1}
+	}
+	return tv.InputNumber{tv.Special, 1}
+}
+
+func clamp(val int) int {
+	switch {
+	case val < 0:
+		return 0
+	case val > 100:
+		return 100
+	default:
+		return val
+	}
+}
+
+type vizioTV struct {
+	config *Config
+	base   *url.URL
+	client *http.Client
+
+	mu        sync.Mutex
+	authToken string
+	// pairing holds the challenge issued by BeginPairing until the PIN
+	// arrives.
+	pairing *pairingStartItem
+}
+
+func newVizioTV(config *Config) *vizioTV {
+	host := config.Address
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, "7345")
+	}
+
+	vizio := &vizioTV{
+		config: config,
+		base:   &url.URL{Scheme: "https", Host: host},
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+			Transport: &http.Transport{
+				// SmartCast sets present a self-signed certificate.
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			},
+		},
+	}
+	if token, err := ioutil.ReadFile(config.TokenFile); err == nil {
+		vizio.authToken = strings.TrimSpace(string(token))
+	}
+	return vizio
+}
+
+// call performs a single SmartCast request, decoding the reply envelope
+// and surfacing any failure it reports.
+func (vizio *vizioTV) call(method, path string, body interface{}) (*response, error) {
+	var rd io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		rd = bytes.NewReader(b)
+	}
+
+	u := *vizio.base
+	u.Path = path
+	req, err := http.NewRequest(method, u.String(), rd)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	vizio.mu.Lock()
+	if vizio.authToken != "" {
+		req.Header.Set(headerAuth, vizio.authToken)
+	}
+	vizio.mu.Unlock()
+
+	resp, err := vizio.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var r response
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, fmt.Errorf("vizio: %s %s: %s", method, path, resp.Status)
+	}
+	if r.Status.Result != resultSuccess {
+		return nil, fmt.Errorf("vizio: %s %s: %s", method, path, strings.ToLower(r.Status.Result))
+	}
+	return &r, nil
+}
+
+func (vizio *vizioTV) BeginPairing() error {
+	resp, err := vizio.call("PUT", pathPairingStart, &pairingStartRequest{
+		DeviceID:   vizio.config.DeviceID,
+		DeviceName: "avantgarde",
+	})
+	if err != nil {
+		return err
+	}
+
+	var item pairingStartItem
+	if err := json.Unmarshal(resp.Item, &item); err != nil {
+		return err
+	}
+
+	vizio.mu.Lock()
+	vizio.pairing = &item
+	vizio.mu.Unlock()
+	return nil
+}
+
+func (vizio *vizioTV) FinishPairing(pin string) error {
+	vizio.mu.Lock()
+	pairing := vizio.pairing
+	vizio.mu.Unlock()
+	if pairing == nil {
+		return errors.New("vizio: pairing has not begun")
+	}
+
+	resp, err := vizio.call("PUT", pathPairingPair, &pairingPairRequest{
+		DeviceID:        vizio.config.DeviceID,
+		ChallengeType:   pairing.ChallengeType,
+		ResponseValue:   pin,
+		PairingReqToken: pairing.PairingReqToken,
+	})
+	if err != nil {
+		return err
+	}
+
+	var item pairingPairItem
+	if err := json.Unmarshal(resp.Item, &item); err != nil {
+		return err
+	}
+	if item.AuthToken == "" {
+		return errors.New("vizio: pairing yielded no auth token")
+	}
+
+	vizio.mu.Lock()
+	vizio.authToken = item.AuthToken
+	vizio.pairing = nil
+	vizio.mu.Unlock()
+
+	return ioutil.WriteFile(vizio.config.TokenFile, []byte(item.AuthToken+"\n"), 0600)
+}
+
+func (vizio *vizioTV) keypress(k keyCode) error {
+	_, err := vizio.call("PUT", pathKeyCommand, &keyCommandRequest{
+		KeyList: []keyPress{{k, actionPress}},
+	})
+	return err
+}
+
+func (vizio *vizioTV) setting(path string) (*settingItem, error) {
+	resp, err := vizio.call("GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Items) == 0 {
+		return nil, fmt.Errorf("vizio: %s: no such setting", path)
+	}
+	return &resp.Items[0], nil
+}
+
+// modify changes a menu setting; SmartCast insists on the setting's current
+// hash to guard against concurrent changes.
+func (vizio *vizioTV) modify(path string, value interface{}) error {
+	item, err := vizio.setting(path)
+	if err != nil {
+		return err
+	}
+	_, err = vizio.call("PUT", path, &modifyRequest{requestModify, item.HashVal, value})
+	return err
+}
+
+func (vizio *vizioTV) Do(op *tv.Op) error {
+	switch op.Attribute {
+	case tv.Power:
+		if op.Value.(bool) {
+			return vizio.keypress(keyPowerOn)
+		}
+		return vizio.keypress(keyPowerOff)
+	case tv.Volume:
+		switch op.Operator {
+		case tv.Set:
+			return vizio.modify(pathVolume, clamp(op.Value.(int)))
+		case tv.Increment:
+			return vizio.keypress(keyVolumeUp)
+		case tv.Decrement:
+			return vizio.keypress(keyVolumeDown)
+		}
+	case tv.Mute:
+		switch op.Operator {
+		case tv.Set:
+			if op.Value.(bool) {
+				return vizio.keypress(keyMuteOn)
+			}
+			return vizio.keypress(keyMuteOff)
+		case tv.Toggle:
+			return vizio.keypress(keyMuteToggle)
+		}
+	case tv.Input:
+		if name := inputName(op.Value.(tv.InputNumber)); name != "" {
+			return vizio.modify(pathCurrentInput, name)
+		}
+	case tv.Raw:
+		// Raw commands are a key's codeset and code, e.g. "11 2".
+		var k keyCode
+		if _, err := fmt.Sscanf(string(op.Value.([]byte)), "%d %d", &k.Codeset, &k.Code); err != nil {
+			return fmt.Errorf("vizio: raw commands must be a codeset and a code: %w", tv.ErrInvalidValue)
+		}
+		return vizio.keypress(k)
+	}
+	return fmt.Errorf("vizio: %w", tv.ErrUnsupported)
+}
+
+func (vizio *vizioTV) State() (*tv.State, error) {
+	state := &tv.State{}
+
+	power, err := vizio.setting(pathPowerMode)
+	if err != nil {
+		return nil, err
+	}
+	var mode int
+	json.Unmarshal(power.Value, &mode)
+	state.Power = mode == 1
+	if !state.Power {
+		return state, nil
+	}
+
+	volume, err := vizio.setting(pathVolume)
+	if err != nil {
+		return nil, err
+	}
+	json.Unmarshal(volume.Value, &state.Volume)
+
+	mute, err := vizio.setting(pathMute)
+	if err != nil {
+		return nil, err
+	}
+	var muteValue string
+	json.Unmarshal(mute.Value, &muteValue)
+	state.Mute = muteValue == muteOn
+
+	input, err := vizio.setting(pathCurrentInput)
+	if err != nil {
+		return nil, err
+	}
+	var inputValue string
+	json.Unmarshal(input.Value, &inputValue)
+	state.Input = nameInput(inputValue)
+
+	return state, nil
+}
+
+var capabilities = []tv.Capability{
+	{Attribute: tv.Power, Operators: []tv.Operator{tv.Set}},
+	{Attribute: tv.Volume, Operators: []tv.Operator{tv.Set, tv.Increment, tv.Decrement}, Min: 0, Max: 100},
+	{Attribute: tv.Mute, Operators: []tv.Operator{tv.Set, tv.Toggle}},
+	{Attribute: tv.Input, Operators: []tv.Operator{tv.Set}},
+	{Attribute: tv.Raw, Operators: []tv.Operator{tv.Set}},
+}
+
+func (vizio *vizioTV) Capabilities() []tv.Capability {
+	return capabilities
+}
+
+func init() {
+	tv.RegisterModel("vizio", &smartcastModel{})
+}
@@ -0,0 +1,175 @@
+package vizio
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/DHowett/avantgarde/tv"
+)
+
+const (
+	fakePIN   = "1234"
+	fakeToken = "Zmc2ZDRhMjkz"
+)
+
+// fakeSmartCast pairs with fakePIN and keeps a volume and an input that
+// can be read and modified, rejecting stale hashes as a real set does.
+type fakeSmartCast struct {
+	mu     sync.Mutex
+	keys   []keyCode
+	volume int
+	input  string
+	hash   int64
+}
+
+func (f *fakeSmartCast) reply(w http.ResponseWriter, result string, item interface{}, items ...settingItem) {
+	env := map[string]interface{}{"STATUS": map[string]string{"RESULT": result}}
+	if item != nil {
+		env["ITEM"] = item
+	}
+	if items != nil {
+		env["ITEMS"] = items
+	}
+	json.NewEncoder(w).Encode(env)
+}
+
+func (f *fakeSmartCast) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.URL.Path {
+	case pathPairingStart:
+		f.reply(w, resultSuccess, &pairingStartItem{PairingReqToken: 42, ChallengeType: 1})
+		return
+	case pathPairingPair:
+		var req pairingPairRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.ResponseValue != fakePIN || req.PairingReqToken != 42 {
+			f.reply(w, "INVALID_PIN", nil)
+			return
+		}
+		f.reply(w, resultSuccess, &pairingPairItem{AuthToken: fakeToken})
+		return
+	}
+
+	if r.Header.Get(headerAuth) != fakeToken {
+		f.reply(w, "BLOCKED", nil)
+		return
+	}
+
+	raw := func(v interface{}) json.RawMessage {
+		b, _ := json.Marshal(v)
+		return b
+	}
+
+	switch {
+	case r.URL.Path == pathKeyCommand:
+		var req keyCommandRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		for _, k := range req.KeyList {
+			f.keys = append(f.keys, k.keyCode)
+		}
+		f.reply(w, resultSuccess, nil)
+	case r.URL.Path == pathPowerMode:
+		f.reply(w, resultSuccess, nil, settingItem{Value: raw(1)})
+	case r.URL.Path == pathMute:
+		f.reply(w, resultSuccess, nil, settingItem{HashVal: f.hash, Value: raw("Off")})
+	case r.Method == "GET" && r.URL.Path == pathVolume:
+		f.reply(w, resultSuccess, nil, settingItem{HashVal: f.hash, Value: raw(f.volume)})
+	case r.Method == "GET" && r.URL.Path == pathCurrentInput:
+		f.reply(w, resultSuccess, nil, settingItem{HashVal: f.hash, Value: raw(f.input)})
+	case r.Method == "PUT":
+		var req modifyRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Request != requestModify || req.HashVal != f.hash {
+			f.reply(w, "HASHVAL_ERROR", nil)
+			return
+		}
+		f.hash++
+		if r.URL.Path == pathVolume {
+			f.volume = int(req.Value.(float64))
+		} else {
+			f.input = req.Value.(string)
+		}
+		f.reply(w, resultSuccess, nil)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestSmartCast(t *testing.T) {
+	fake := &fakeSmartCast{volume: 20, input: "HDMI-1", hash: 100}
+	srv := httptest.NewTLSServer(fake)
+	defer srv.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "vizio.token")
+	v, err := (&smartcastModel{}).Initialize(nil, &Config{
+		Address:   strings.TrimPrefix(srv.URL, "https://"),
+		DeviceID:  "avantgarde-test",
+		TokenFile: tokenFile,
+	})
+	if err != nil {
+		t.Fatalf("Failed to initialize: %v", err)
+	}
+
+	if err := v.Do(&tv.Op{tv.Power, tv.Set, true}); err == nil {
+		t.Errorf("An unpaired set accepted a command!")
+	}
+
+	pairer := v.(tv.Pairer)
+	if err := pairer.BeginPairing(); err != nil {
+		t.Fatalf("Failed to begin pairing: %v", err)
+	}
+	if err := pairer.FinishPairing("0000"); err == nil {
+		t.Errorf("Pairing succeeded with the wrong PIN!")
+	}
+	if err := pairer.FinishPairing(fakePIN); err != nil {
+		t.Fatalf("Failed to finish pairing: %v", err)
+	}
+
+	token, err := ioutil.ReadFile(tokenFile)
+	if err != nil || strings.TrimSpace(string(token)) != fakeToken {
+		t.Errorf("Got token %q (%v) instead of %s!", token, err, fakeToken)
+	}
+
+	// A fresh instance should pick the token back up from disk.
+	v, _ = (&smartcastModel{}).Initialize(nil, &Config{
+		Address:   strings.TrimPrefix(srv.URL, "https://"),
+		TokenFile: tokenFile,
+	})
+
+	ops := []*tv.Op{
+		{tv.Power, tv.Set, true},
+		{tv.Volume, tv.Increment, 1},
+		{tv.Mute, tv.Toggle, nil},
+		{tv.Volume, tv.Set, 35},
+		{tv.Input, tv.Set, tv.InputNumber{tv.HDMI, 3}},
+		{tv.Raw, tv.Set, []byte("7 1")},
+	}
+	for _, op := range ops {
+		if err := v.Do(op); err != nil {
+			t.Errorf("Failed to do %v: %v", op, err)
+		}
+	}
+
+	expect := []keyCode{keyPowerOn, keyVolumeUp, keyMuteToggle, {7, 1}}
+	if fmt.Sprint(fake.keys) != fmt.Sprint(expect) {
+		t.Errorf("Got key presses %v instead of %v!", fake.keys, expect)
+	}
+
+	state, err := v.State()
+	if err != nil {
+		t.Fatalf("Failed to get state: %v", err)
+	}
+	want := tv.State{Power: true, Volume: 35, Input: tv.InputNumber{tv.HDMI, 3}}
+	if *state != want {
+		t.Errorf("Got state %+v instead of %+v!", *state, want)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"html/template"
+	"io/fs"
+	"net/http"
+)
+
+// web holds the remote control page served at /. Its scripts and styles
+// are served beneath /ui/.
+//
+//go:embed web
+var web embed.FS
+
+var uiTemplate = template.Must(template.ParseFS(web, "web/index.html"))
+
+// uiPage is what index.html is rendered with; it tells the script which
+// connection names the server understands.
+type uiPage struct {
+	Connections string
+}
+
+func newUIHandler() http.Handler {
+	static, err := fs.Sub(web, "web")
+	if err != nil {
+		panic(err)
+	}
+	connections, _ := json.Marshal(tvInputNames)
+	page := &uiPage{Connections: string(connections)}
+
+	mux := http.NewServeMux()
+	mux.Handle("/ui/", http.StripPrefix("/ui/", http.FileServer(http.FS(static))))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != "GET" && r.Method != "HEAD" {
+			w.Header().Set("Allow", "GET")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		uiTemplate.Execute(w, page)
+	})
+	return mux
+}
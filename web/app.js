@@ -0,0 +1,241 @@
+// The avantgarde remote control. It lists the configured TVs, offers the
+// controls each one supports, and follows their state on /events.
+"use strict";
+
+const connections = JSON.parse(document.body.dataset.connections);
+const cards = new Map();
+
+let token = localStorage.getItem("avantgarde-token") || "";
+
+function headers(extra) {
+	const h = Object.assign({}, extra);
+	if (token) {
+		h["Authorization"] = "Bearer " + token;
+	}
+	return h;
+}
+
+function setStatus(text) {
+	document.getElementById("status").textContent = text;
+}
+
+// askForToken shows the sign-in form; signing in starts everything over.
+function askForToken() {
+	const form = document.getElementById("token");
+	form.hidden = false;
+	form.onsubmit = (e) => {
+		e.preventDefault();
+		token = form.elements.token.value;
+		localStorage.setItem("avantgarde-token", token);
+		form.hidden = true;
+		start();
+	};
+}
+
+async function request(path, options) {
+	const resp = await fetch(path, Object.assign({}, options, {headers: headers(options && options.headers)}));
+	if (resp.status === 401) {
+		askForToken();
+		throw new Error("a valid token is required");
+	}
+	return resp;
+}
+
+async function post(card, command, fields) {
+	const body = new URLSearchParams(fields);
+	card.querySelector(".error").textContent = "";
+	try {
+		const resp = await request("tv/" + encodeURIComponent(card.dataset.id) + "/" + command, {
+			method: "POST",
+			headers: {"Content-Type": "application/x-www-form-urlencoded"},
+			body: body,
+		});
+		if (!resp.ok) {
+			throw new Error(resp.status + " " + (await resp.text() || resp.statusText));
+		}
+	} catch (err) {
+		card.querySelector(".error").textContent = command + ": " + err.message;
+	}
+}
+
+// actions selects the elements in a control that send commands. Each sends
+// the operator in its data-operator attribute, or else "set".
+const actions = "[data-post], [data-slider], [data-input], [data-channel]";
+
+// showControls hides the controls for attributes a TV does not support, and
+// the buttons and sliders whose operator it does not accept, like the volume
+// slider on a TV that can only step its volume. TVs that do not list their
+// capabilities keep every control.
+function showControls(card, t) {
+	if (!t.capabilities) {
+		return;
+	}
+	const caps = new Map(t.capabilities.map((c) => [c.attribute, c]));
+	for (const control of card.querySelectorAll(".control")) {
+		const c = caps.get(control.dataset.attribute);
+		for (const action of control.querySelectorAll(actions)) {
+			if (!c || !(c.operators || []).includes(action.dataset.operator || "set")) {
+				action.remove();
+			}
+		}
+		if (!control.querySelector(actions)) {
+			control.remove();
+			continue;
+		}
+		const slider = control.querySelector("input[type=range]");
+		if (slider && c.max !== undefined) {
+			slider.min = c.min;
+			slider.max = c.max;
+		}
+	}
+}
+
+function fillInputs(card, t) {
+	const select = card.querySelector("[data-input]");
+	if (!select) {
+		return;
+	}
+	const input = t.capabilities && t.capabilities.find((c) => c.attribute === "input");
+	let options = input && input.inputs;
+	if (!options) {
+		options = [];
+		for (const connection of connections) {
+			for (let n = 1; n <= 4; n++) {
+				options.push({connection: connection, number: n});
+			}
+		}
+	}
+	select.append(new Option("", ""));
+	for (const i of options) {
+		select.append(new Option(i.connection + " " + i.number, i.connection + " " + i.number));
+	}
+	select.onchange = () => {
+		if (select.value) {
+			const [c, n] = select.value.split(" ");
+			post(card, "input", {c: c, n: n});
+		}
+	};
+}
+
+function newCard(t) {
+	const card = document.getElementById("tv-card").content.firstElementChild.cloneNode(true);
+	card.dataset.id = t.name || String(t.id);
+	card.querySelector(".name").textContent = t.name || "TV " + t.id;
+	card.querySelector(".model").textContent = t.model;
+	card.querySelector(".connection").textContent = t.connection;
+	showControls(card, t);
+	fillInputs(card, t);
+
+	for (const button of card.querySelectorAll("[data-post]")) {
+		const fields = {};
+		for (const key of ["v", "d"]) {
+			if (button.dataset[key]) {
+				fields[key] = button.dataset[key];
+			}
+		}
+		button.onclick = () => post(card, button.dataset.post, fields);
+	}
+	for (const slider of card.querySelectorAll("[data-slider]")) {
+		slider.onchange = () => post(card, slider.dataset.slider, {v: slider.value});
+	}
+	const channel = card.querySelector("[data-channel]");
+	if (channel) {
+		channel.onsubmit = (e) => {
+			e.preventDefault();
+			post(card, "channel", {v: channel.elements.v.value});
+		};
+	}
+	return card;
+}
+
+function channelName(ch) {
+	if (ch === null || ch === undefined) {
+		return "";
+	}
+	if (typeof ch === "object") {
+		return ch.Ch + "." + ch.Sub;
+	}
+	return String(ch);
+}
+
+function showState(ev) {
+	const card = cards.get(ev.id);
+	if (!card) {
+		return;
+	}
+	card.querySelector(".connection").textContent = ev.connection;
+	card.querySelector(".error").textContent = ev.error || "";
+	if (!ev.state) {
+		return;
+	}
+	const values = {
+		power: ev.state.Power ? "on" : "off",
+		volume: String(ev.state.Volume),
+		mute: ev.state.Mute ? "muted" : "",
+		input: ev.input ? ev.input.connection + " " + ev.input.number : "",
+		channel: channelName(ev.state.Channel),
+	};
+	for (const output of card.querySelectorAll("[data-state]")) {
+		output.textContent = values[output.dataset.state];
+	}
+	const volume = card.querySelector("[data-slider=volume]");
+	if (volume && document.activeElement !== volume) {
+		volume.value = ev.state.Volume;
+	}
+}
+
+// follow reads server-sent events with fetch rather than EventSource, which
+// cannot send a token, and reconnects when the stream ends.
+async function follow() {
+	try {
+		const resp = await request("events");
+		if (!resp.ok) {
+			throw new Error(resp.statusText);
+		}
+		setStatus("live");
+		const reader = resp.body.pipeThrough(new TextDecoderStream()).getReader();
+		let buffer = "";
+		for (;;) {
+			const {value, done} = await reader.read();
+			if (done) {
+				break;
+			}
+			buffer += value;
+			let end;
+			while ((end = buffer.indexOf("\n\n")) >= 0) {
+				const message = buffer.slice(0, end);
+				buffer = buffer.slice(end + 2);
+				const data = message.split("\n").filter((l) => l.startsWith("data: ")).map((l) => l.slice(6)).join("\n");
+				if (data) {
+					showState(JSON.parse(data));
+				}
+			}
+		}
+	} catch (err) {
+		if (!document.getElementById("token").hidden) {
+			return;
+		}
+	}
+	setStatus("reconnecting…");
+	setTimeout(follow, 5000);
+}
+
+async function start() {
+	const main = document.getElementById("tvs");
+	main.textContent = "";
+	cards.clear();
+	try {
+		const resp = await request("tvs");
+		for (const t of await resp.json()) {
+			const card = newCard(t);
+			cards.set(t.id, card);
+			main.append(card);
+		}
+	} catch (err) {
+		setStatus(err.message);
+		return;
+	}
+	follow();
+}
+
+start();